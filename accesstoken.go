@@ -0,0 +1,77 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTokenType is returned by the rule created with
+// WithAccessTokenTypeRule when the 'typ' header parameter of the token does
+// not identify it as a JWT access token, such as when an ID token is
+// presented as an access token.
+var ErrInvalidTokenType = errors.New("token is not a JWT access token")
+
+// accessTokenType is the media type of JWT access tokens registered by RFC
+// 9068, without the "application/" prefix.
+const accessTokenType = "at+jwt"
+
+// ParseAndVerifyAccessToken will parse the access token and verify it as
+// described in section 4 of RFC 9068, the JWT profile for OAuth 2.0 access
+// tokens, in addition to verifying it using the provided rules. It always
+// verifies that:
+//
+//   - The 'typ' header parameter is "at+jwt" or "application/at+jwt", which
+//     rejects other kinds of tokens issued by the same issuer, such as ID
+//     tokens.
+//   - The 'iss' claim equals the issuer of the Verifier.
+//   - The 'aud' claim contains the given audience.
+//   - The token is not expired and was not issued in the future, allowing for
+//     the leeway set with WithLeeway.
+//   - The 'sub', 'client_id' and 'jti' claims are present.
+func (j Verifier) ParseAndVerifyAccessToken(
+	ctx context.Context,
+	token string,
+	audience string,
+	rules ...ClaimRule,
+) (JWT, error) {
+	return j.ParseAndVerify(ctx, token, append(j.accessTokenProfileRules(audience), rules...)...)
+}
+
+func (j Verifier) accessTokenProfileRules(audience string) []ClaimRule {
+	return []ClaimRule{
+		WithAccessTokenTypeRule(),
+		j.WithIssuerRule(),
+		WithAudienceRule(audience),
+		j.WithExpirationRule(j.leeway),
+		j.WithIssuedAtRule(j.leeway),
+		WithRequiredClaimRule("sub"),
+		WithRequiredClaimRule("client_id"),
+		WithRequiredClaimRule("jti"),
+	}
+}
+
+// WithAccessTokenTypeRule will verify that the 'typ' header parameter of the
+// token is "at+jwt" or "application/at+jwt", as required by section 2.1 of
+// RFC 9068. The comparison is case-insensitive, since 'typ' is a media type.
+// Tokens without a 'typ' header parameter fail the rule.
+func WithAccessTokenTypeRule() ClaimRule {
+	return WithTokenRule("access_token_type", func(token JWT) error {
+		typ, ok := token.Header["typ"].(string)
+		if !ok {
+			return &sentinelError{
+				sentinel: ErrInvalidTokenType,
+				err:      fmt.Errorf("expected header 'typ' to be '%s' but it is not present", accessTokenType),
+			}
+		}
+
+		if !isMediaType(typ, accessTokenType) {
+			return &sentinelError{
+				sentinel: ErrInvalidTokenType,
+				err:      fmt.Errorf("expected header 'typ' to be '%s' but got '%s'", accessTokenType, typ),
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,177 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_ParseAndVerifyAccessToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	cases := map[string]struct {
+		typ       any
+		claims    jwt.MapClaims
+		remove    []string
+		wantErr   string
+		wantErrIs error
+	}{
+		"valid": {
+			typ: "at+jwt",
+		},
+		"valid/media type": {
+			typ: "application/at+jwt",
+		},
+		"valid/case-insensitive": {
+			typ: "Application/AT+JWT",
+		},
+		"id token": {
+			typ:       "JWT",
+			wantErr:   "expected header 'typ' to be 'at+jwt' but got 'JWT'",
+			wantErrIs: ErrInvalidTokenType,
+		},
+		"no type": {
+			wantErr:   "expected header 'typ' to be 'at+jwt' but it is not present",
+			wantErrIs: ErrInvalidTokenType,
+		},
+		"other media type": {
+			typ:       "application/jwt",
+			wantErr:   "expected header 'typ' to be 'at+jwt' but got 'application/jwt'",
+			wantErrIs: ErrInvalidTokenType,
+		},
+		"wrong audience": {
+			typ:     "at+jwt",
+			claims:  jwt.MapClaims{"aud": "https://other.example.com"},
+			wantErr: "claim 'aud' is invalid",
+		},
+		"expired": {
+			typ:       "at+jwt",
+			claims:    jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()},
+			wantErrIs: ErrTokenExpired,
+		},
+		"missing client_id": {
+			typ:     "at+jwt",
+			remove:  []string{"client_id"},
+			wantErr: "claim 'client_id' not found",
+		},
+		"missing jti": {
+			typ:     "at+jwt",
+			remove:  []string{"jti"},
+			wantErr: "claim 'jti' not found",
+		},
+		"missing sub": {
+			typ:     "at+jwt",
+			remove:  []string{"sub"},
+			wantErr: "claim 'sub' not found",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := jwt.MapClaims{
+				"iss":       issuer.URL,
+				"aud":       "https://api.example.com",
+				"sub":       "00u1",
+				"client_id": "0oa1",
+				"jti":       "AT.1",
+				"iat":       time.Now().Unix(),
+				"exp":       time.Now().Add(time.Hour).Unix(),
+			}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+			for _, k := range tt.remove {
+				delete(claims, k)
+			}
+
+			token := signWithoutType(t, issuer, claims)
+			if tt.typ != nil {
+				token = issuer.sign(t, claims, map[string]any{"typ": tt.typ})
+			}
+
+			parsed, err := issuer.verifier().ParseAndVerifyAccessToken(
+				context.Background(),
+				token,
+				"https://api.example.com",
+			)
+			if tt.wantErr != "" || tt.wantErrIs != nil {
+				require.Error(t, err)
+				if tt.wantErr != "" {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "00u1", parsed.Claims["sub"])
+		})
+	}
+}
+
+func TestWithAccessTokenProfile(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	claims := jwt.MapClaims{
+		"iss":       issuer.URL,
+		"aud":       "https://api.example.com",
+		"sub":       "00u1",
+		"client_id": "0oa1",
+		"jti":       "AT.1",
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+
+	cases := map[string]struct {
+		token      string
+		wantStatus int
+	}{
+		"access token": {
+			token:      issuer.sign(t, claims, map[string]any{"typ": "at+jwt"}),
+			wantStatus: http.StatusOK,
+		},
+		"id token": {
+			token:      issuer.sign(t, claims),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := issuer.verifier().Middleware(nil, WithAccessTokenProfile("https://api.example.com"))(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+// signWithoutType signs the claims like testIssuer.sign, but without the 'typ'
+// header parameter.
+func signWithoutType(t *testing.T, issuer *testIssuer, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = issuer.kid
+	delete(token.Header, "typ")
+
+	signed, err := token.SignedString(issuer.key)
+	require.NoError(t, err)
+
+	return signed
+}
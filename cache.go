@@ -2,6 +2,7 @@ package verifier
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -12,6 +13,22 @@ const (
 	defaultCacheCleanupInterval = 10 * time.Minute
 )
 
+// ErrReplayProtectionUnsupported is returned when a token or proof must be
+// protected against replay, such as a DPoP proof or a logout token, but the
+// Cache of the Verifier does not implement AddCache, such as NopCache.
+var ErrReplayProtectionUnsupported = errors.New("cache does not support replay protection")
+
+// AddCache is a Cache that can atomically add an item only if the cache does
+// not already contain an item with the same key. It is required to protect
+// DPoP proofs and logout tokens against replay, since a separate Get and Set
+// would let concurrent requests with the same proof or token both succeed.
+type AddCache interface {
+	Cache
+	// Add adds an item to the cache if there is no item with the same key,
+	// and reports whether it was added.
+	Add(ctx context.Context, key string, value any) bool
+}
+
 // DefaultCache uses an in-memory key-value cache.
 type DefaultCache struct {
 	*cache.Cache
@@ -37,7 +54,17 @@ func (d DefaultCache) Get(_ context.Context, key string) (any, bool) {
 	return d.Cache.Get(key)
 }
 
-// NopCache is a no-op implementation of Cache.
+// Add adds an item to the cache if there is no unexpired item with the same
+// key, and reports whether it was added. Uses the underlying Cache's default
+// expiration.
+func (d DefaultCache) Add(_ context.Context, key string, value any) bool {
+	return d.Cache.Add(key, value, cache.DefaultExpiration) == nil
+}
+
+// NopCache is a no-op implementation of Cache. Since it does not implement
+// AddCache, a Verifier that uses it cannot protect DPoP proofs and logout
+// tokens against replay, so verifying them fails with
+// ErrReplayProtectionUnsupported.
 type NopCache struct{}
 
 // NewNopCache creates a new NopCache.
@@ -52,3 +79,15 @@ func (n NopCache) Set(context.Context, string, any) {}
 func (n NopCache) Get(context.Context, string) (any, bool) {
 	return nil, false
 }
+
+// markUsed atomically records the key as used and reports whether it was not
+// used before. It fails with ErrReplayProtectionUnsupported if the cache does
+// not implement AddCache.
+func (j Verifier) markUsed(ctx context.Context, key string) (bool, error) {
+	c, ok := j.cache.(AddCache)
+	if !ok {
+		return false, ErrReplayProtectionUnsupported
+	}
+
+	return c.Add(ctx, key, true), nil
+}
@@ -0,0 +1,124 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+)
+
+// AllOf returns a ClaimRule which passes only if all of the given rules
+// pass. If any of them fail, the error will contain the errors of all of the
+// rules that failed. Context-aware rules are verified concurrently, in the
+// same way as by [Verifier.ParseAndVerify].
+//
+// This is mostly useful for nesting within [AnyOf], [Not] and [If], since
+// all of the rules passed into [Verifier.ParseAndVerify] must already pass.
+func AllOf(rules ...ClaimRule) ClaimRule {
+	return ClaimRule{
+		name: "all_of",
+		token: func(ctx context.Context, token JWT) error {
+			if verr := verifyRules(ctx, token, rules); verr != nil {
+				return fmt.Errorf("all of the rules must pass: (%w)", verr)
+			}
+
+			return nil
+		},
+		contextAware: isContextAware(rules...),
+	}
+}
+
+// AnyOf returns a ClaimRule which passes if at least one of the given rules
+// passes. If all of them fail, the error will contain the errors of every
+// rule.
+func AnyOf(rules ...ClaimRule) ClaimRule {
+	return ClaimRule{
+		name: "any_of",
+		token: func(ctx context.Context, token JWT) error {
+			verr := &ValidationError{Failures: make([]ClaimFailure, 0, len(rules))}
+			for _, rule := range rules {
+				err := rule.verify(ctx, token)
+				if err == nil {
+					return nil
+				}
+				verr.Failures = append(verr.Failures, *err.(*ClaimFailure))
+			}
+
+			return fmt.Errorf("at least one of the rules must pass: (%w)", verr)
+		},
+		contextAware: isContextAware(rules...),
+	}
+}
+
+// Not returns a ClaimRule which passes only if the given rule fails,
+// including when the claim that the rule verifies is not present. If the rule
+// could not decide whether the token is valid, such as when a context-aware
+// rule times out or returns an error that is not a [ValueError] and does not
+// wrap [ErrRuleFailed], then the returned rule fails too.
+func Not(rule ClaimRule) ClaimRule {
+	return ClaimRule{
+		name: "not",
+		token: func(ctx context.Context, token JWT) error {
+			if err := rule.verify(ctx, token); err != nil {
+				if isIndeterminate(err) {
+					return fmt.Errorf("%s could not be verified: %w", describeRule(rule), err)
+				}
+				return nil
+			}
+
+			return fmt.Errorf("%s must not pass", describeRule(rule))
+		},
+		contextAware: rule.contextAware,
+	}
+}
+
+// If returns a ClaimRule which verifies the token according to then only if
+// the token passes the condition rule. If the token does not pass the
+// condition then the rule passes, unless the condition could not decide
+// whether the token is valid, in the same way as for [Not].
+//
+// For instance, the following will require the 'scp' claim to contain
+// 'batch' only for tokens issued to the 'batch-client' client:
+//
+//	If(
+//		WithClientIDRule("batch-client"),
+//		WithAllScopesRule("batch"),
+//	)
+func If(condition, then ClaimRule) ClaimRule {
+	return ClaimRule{
+		name: "if",
+		token: func(ctx context.Context, token JWT) error {
+			if err := condition.verify(ctx, token); err != nil {
+				if isIndeterminate(err) {
+					return fmt.Errorf("%s could not be verified: %w", describeRule(condition), err)
+				}
+				return nil
+			}
+
+			if err := then.verify(ctx, token); err != nil {
+				return fmt.Errorf("%s passed so: %w", describeRule(condition), err)
+			}
+
+			return nil
+		},
+		contextAware: isContextAware(condition, then),
+	}
+}
+
+func describeRule(rule ClaimRule) string {
+	switch {
+	case rule.Key != "":
+		return fmt.Sprintf("rule on claim '%s'", rule.Key)
+	case rule.name != "":
+		return fmt.Sprintf("rule '%s'", rule.name)
+	default:
+		return "rule"
+	}
+}
+
+func isContextAware(rules ...ClaimRule) bool {
+	for _, rule := range rules {
+		if rule.contextAware {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCombinators(t *testing.T) {
+	claims := map[string]any{
+		"cid":    "batch-client",
+		"role":   "reader",
+		"groups": []any{"Everyone", "Ops"},
+		"scp":    []any{"openid", "read"},
+	}
+
+	lookupFailed := func(context.Context, any) error {
+		return errors.New("db down")
+	}
+	ruleFailed := func(context.Context, any) error {
+		return fmt.Errorf("client is suspended: %w", ErrRuleFailed)
+	}
+	slow := func(ctx context.Context, _ any) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	cases := map[string]struct {
+		rule    ClaimRule
+		wantErr string
+	}{
+		"all of/passes": {
+			rule: AllOf(
+				WithCustomClaimExactMatchRule("role", "reader"),
+				WithCustomClaimContainsRule("groups", []string{"Ops"}),
+			),
+		},
+		"all of/fails": {
+			rule: AllOf(
+				WithCustomClaimExactMatchRule("role", "admin"),
+				WithCustomClaimContainsRule("groups", []string{"Ops"}),
+				WithCustomClaimExactMatchRule("tenant", "acme"),
+			),
+			wantErr: "all of the rules must pass: (claim 'role' is invalid: expected 'admin' but got 'reader'; claim 'tenant' not found)",
+		},
+		"any of/passes": {
+			rule: AnyOf(
+				WithCustomClaimExactMatchRule("role", "admin"),
+				WithCustomClaimContainsRule("groups", []string{"Ops"}),
+			),
+		},
+		"any of/fails": {
+			rule: AnyOf(
+				WithCustomClaimExactMatchRule("role", "admin"),
+				WithCustomClaimContainsRule("groups", []string{"Admins"}),
+			),
+			wantErr: "at least one of the rules must pass: (claim 'role' is invalid: expected 'admin' but got 'reader'; claim 'groups' is invalid: missing value(s): 'Admins')",
+		},
+		"not/passes": {
+			rule: Not(WithCustomClaimExactMatchRule("role", "admin")),
+		},
+		"not/passes when claim is missing": {
+			rule: Not(ClaimRule{Key: "act"}),
+		},
+		"not/fails": {
+			rule:    Not(WithCustomClaimExactMatchRule("role", "reader")),
+			wantErr: "rule on claim 'role' must not pass",
+		},
+		"not/passes when context rule fails": {
+			rule: Not(WithContextRule("cid", ruleFailed)),
+		},
+		"not/passes when context rule returns a value error": {
+			rule: Not(WithContextRule("cid", func(context.Context, any) error {
+				return &ValueError{Expected: "other-client", Got: "batch-client"}
+			})),
+		},
+		"not/fails when context rule lookup fails": {
+			rule:    Not(WithContextRule("cid", lookupFailed)),
+			wantErr: "rule on claim 'cid' could not be verified: claim 'cid' is invalid: db down",
+		},
+		"not/fails when context token rule lookup fails": {
+			rule: Not(WithContextTokenRule("lookup", func(context.Context, JWT) error {
+				return errors.New("db down")
+			})),
+			wantErr: "rule 'lookup' could not be verified: rule 'lookup' failed: db down",
+		},
+		"not/fails when context rule times out": {
+			rule:    Not(WithTimeout(WithContextRule("cid", slow), time.Millisecond)),
+			wantErr: "rule on claim 'cid' could not be verified: claim 'cid' is invalid: context deadline exceeded",
+		},
+		"not/fails when nested context rule lookup fails": {
+			rule: Not(AnyOf(
+				WithCustomClaimExactMatchRule("role", "admin"),
+				WithContextRule("cid", lookupFailed),
+			)),
+			wantErr: "rule 'any_of' could not be verified: at least one of the rules must pass: (claim 'role' is invalid: expected 'admin' but got 'reader'; claim 'cid' is invalid: db down)",
+		},
+		"if/condition fails": {
+			rule: If(
+				WithClientIDRule("other-client"),
+				WithAllScopesRule("batch"),
+			),
+		},
+		"if/condition passes and rule passes": {
+			rule: If(
+				WithClientIDRule("batch-client"),
+				WithAllScopesRule("read"),
+			),
+		},
+		"if/condition passes and rule fails": {
+			rule: If(
+				WithClientIDRule("batch-client"),
+				WithAllScopesRule("batch"),
+			),
+			wantErr: "rule on claim 'cid' passed so: claim 'scp' is invalid: missing scope(s): 'batch'",
+		},
+		"if/context condition fails": {
+			rule: If(
+				WithContextRule("cid", ruleFailed),
+				WithAllScopesRule("admin"),
+			),
+		},
+		"if/fails when context condition lookup fails": {
+			rule: If(
+				WithContextRule("cid", lookupFailed),
+				WithAllScopesRule("admin"),
+			),
+			wantErr: "rule on claim 'cid' could not be verified: claim 'cid' is invalid: db down",
+		},
+		"if/fails when context condition times out": {
+			rule: If(
+				WithTimeout(WithContextRule("cid", slow), time.Millisecond),
+				WithAllScopesRule("admin"),
+			),
+			wantErr: "rule on claim 'cid' could not be verified: claim 'cid' is invalid: context deadline exceeded",
+		},
+		"nested": {
+			rule: AnyOf(
+				AllOf(
+					WithCustomClaimExactMatchRule("role", "admin"),
+					WithAllScopesRule("write"),
+				),
+				Not(WithCustomClaimContainsRule("groups", []string{"Ops"})),
+			),
+			wantErr: "at least one of the rules must pass: (all of the rules must pass: (claim 'role' is invalid: expected 'admin' but got 'reader'; claim 'scp' is invalid: missing scope(s): 'write'); rule on claim 'groups' must not pass)",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tt.rule.verify(context.Background(), JWT{Claims: claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
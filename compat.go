@@ -0,0 +1,101 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// DefaultOktaLeeway is the default leeway in seconds of the official Okta JWT
+// verifier library for the 'exp' and 'iat' claims.
+const DefaultOktaLeeway = 120
+
+// AccessTokenRules returns the rules that reproduce the claim checks of
+// VerifyAccessToken in the official Okta JWT verifier library, which verify
+// the 'iss', 'aud', 'exp' and 'iat' claims, and the 'cid' claim if clientID
+// is not empty. The leeway is in seconds, such as DefaultOktaLeeway.
+func (j Verifier) AccessTokenRules(audience, clientID string, leeway int) []ClaimRule {
+	rules := []ClaimRule{
+		j.WithIssuerRule(),
+		WithAudienceRule(audience),
+	}
+	if clientID != "" {
+		rules = append(rules, WithClientIDRule(clientID))
+	}
+
+	return append(rules, j.WithExpirationRule(leeway), j.WithIssuedAtRule(leeway))
+}
+
+// IDTokenRules returns the rules that reproduce the claim checks of
+// VerifyIdToken in the official Okta JWT verifier library, which verify the
+// 'iss', 'aud', 'nonce', 'exp' and 'iat' claims. The leeway is in seconds,
+// such as DefaultOktaLeeway. Unlike [Verifier.ParseAndVerifyIDToken], the
+// rules do not verify the 'azp' and 'sub' claims.
+func (j Verifier) IDTokenRules(clientID, nonce string, leeway int) []ClaimRule {
+	return []ClaimRule{
+		j.WithIssuerRule(),
+		WithAudienceRule(clientID),
+		WithNonceRule(nonce),
+		j.WithExpirationRule(leeway),
+		j.WithIssuedAtRule(leeway),
+	}
+}
+
+// VerifyAccessToken parses and verifies an access token in the same way as
+// VerifyAccessToken in the official Okta JWT verifier library, using the
+// same map of claims to validate, which must contain 'aud' and may contain
+// 'cid'. It uses the rules returned by [Verifier.AccessTokenRules] with
+// DefaultOktaLeeway, and is intended for migrating from the official library.
+func (j Verifier) VerifyAccessToken(
+	ctx context.Context,
+	token string,
+	claimsToValidate map[string]string,
+) (JWT, error) {
+	if err := checkClaimsToValidate(claimsToValidate, []string{"aud"}, []string{"cid"}); err != nil {
+		return JWT{}, err
+	}
+
+	rules := j.AccessTokenRules(claimsToValidate["aud"], claimsToValidate["cid"], DefaultOktaLeeway)
+
+	return j.ParseAndVerify(ctx, token, rules...)
+}
+
+// VerifyIdToken parses and verifies an ID token in the same way as
+// VerifyIdToken in the official Okta JWT verifier library, using the same map
+// of claims to validate, which must contain 'aud' and 'nonce'. It uses the
+// rules returned by [Verifier.IDTokenRules] with DefaultOktaLeeway, and is
+// intended for migrating from the official library.
+//
+//nolint:revive // The name matches the official library.
+func (j Verifier) VerifyIdToken(
+	ctx context.Context,
+	token string,
+	claimsToValidate map[string]string,
+) (JWT, error) {
+	if err := checkClaimsToValidate(claimsToValidate, []string{"aud", "nonce"}, nil); err != nil {
+		return JWT{}, err
+	}
+
+	rules := j.IDTokenRules(claimsToValidate["aud"], claimsToValidate["nonce"], DefaultOktaLeeway)
+
+	return j.ParseAndVerify(ctx, token, rules...)
+}
+
+// checkClaimsToValidate returns an error if claimsToValidate is missing any of
+// the required claims or contains claims that are neither required nor
+// optional, since those would not be verified.
+func checkClaimsToValidate(claimsToValidate map[string]string, required, optional []string) error {
+	for _, claim := range required {
+		if _, ok := claimsToValidate[claim]; !ok {
+			return fmt.Errorf("claims to validate must contain '%s'", claim)
+		}
+	}
+
+	for claim := range claimsToValidate {
+		if !slices.Contains(required, claim) && !slices.Contains(optional, claim) {
+			return fmt.Errorf("claim '%s' cannot be validated", claim)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_VerifyAccessToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	validClaims := func() jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss": issuer.URL,
+			"aud": "api://default",
+			"cid": "0oa1",
+			"exp": now.Add(time.Hour).Unix(),
+			"iat": now.Unix(),
+		}
+	}
+
+	cases := map[string]struct {
+		modify           func(jwt.MapClaims)
+		claimsToValidate map[string]string
+		wantErr          string
+	}{
+		"valid": {
+			claimsToValidate: map[string]string{"aud": "api://default", "cid": "0oa1"},
+		},
+		"valid/without cid": {
+			claimsToValidate: map[string]string{"aud": "api://default"},
+		},
+		"valid/expired within leeway": {
+			modify:           func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			claimsToValidate: map[string]string{"aud": "api://default"},
+		},
+		"expired": {
+			modify:           func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-5 * time.Minute).Unix() },
+			claimsToValidate: map[string]string{"aud": "api://default"},
+			wantErr:          "claim 'exp' is invalid",
+		},
+		"wrong cid": {
+			claimsToValidate: map[string]string{"aud": "api://default", "cid": "0oa2"},
+			wantErr:          "claim 'cid' is invalid: expected '0oa2' but got '0oa1'",
+		},
+		"missing aud": {
+			claimsToValidate: map[string]string{"cid": "0oa1"},
+			wantErr:          "claims to validate must contain 'aud'",
+		},
+		"unsupported claim": {
+			claimsToValidate: map[string]string{"aud": "api://default", "nonce": "abc"},
+			wantErr:          "claim 'nonce' cannot be validated",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			_, err := issuer.verifier().VerifyAccessToken(
+				context.Background(),
+				issuer.sign(t, claims),
+				tt.claimsToValidate,
+			)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifier_VerifyIdToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	validClaims := func() jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss":   issuer.URL,
+			"aud":   "0oa1",
+			"nonce": "n-0S6_WzA2Mj",
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Add(time.Minute).Unix(),
+		}
+	}
+
+	cases := map[string]struct {
+		modify           func(jwt.MapClaims)
+		claimsToValidate map[string]string
+		wantErr          string
+	}{
+		"valid": {
+			claimsToValidate: map[string]string{"aud": "0oa1", "nonce": "n-0S6_WzA2Mj"},
+		},
+		"wrong nonce": {
+			claimsToValidate: map[string]string{"aud": "0oa1", "nonce": "other"},
+			wantErr:          "claim 'nonce' is invalid: expected 'other' but got 'n-0S6_WzA2Mj'",
+		},
+		"missing nonce": {
+			modify:           func(c jwt.MapClaims) { delete(c, "nonce") },
+			claimsToValidate: map[string]string{"aud": "0oa1", "nonce": "n-0S6_WzA2Mj"},
+			wantErr:          "claim 'nonce' not found",
+		},
+		"issued in future": {
+			modify:           func(c jwt.MapClaims) { c["iat"] = time.Now().Add(5 * time.Minute).Unix() },
+			claimsToValidate: map[string]string{"aud": "0oa1", "nonce": "n-0S6_WzA2Mj"},
+			wantErr:          "claim 'iat' is invalid",
+		},
+		"missing nonce to validate": {
+			claimsToValidate: map[string]string{"aud": "0oa1"},
+			wantErr:          "claims to validate must contain 'nonce'",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			_, err := issuer.verifier().VerifyIdToken(
+				context.Background(),
+				issuer.sign(t, claims),
+				tt.claimsToValidate,
+			)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package verifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	dpopHeader         = "DPoP"
+	dpopType           = "dpop+jwt"
+	cacheKeyPrefixDPoP = "dpop_jti:"
+
+	defaultDPoPMaxAge = time.Minute
+)
+
+// dpopAlgorithms are the asymmetric algorithms that DPoP proofs may be
+// signed with.
+var dpopAlgorithms = []string{
+	"ES256", "ES384", "ES512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"EdDSA",
+}
+
+// ErrInvalidDPoPProof is returned when the DPoP proof of a request is missing
+// or invalid, or when it is not bound to the access token. It corresponds to
+// the invalid_dpop_proof error code described in RFC 9449.
+var ErrInvalidDPoPProof = errors.New("invalid_dpop_proof")
+
+// DPoPOption is used to configure how DPoP proofs are verified.
+type DPoPOption func(*dpopConfig)
+
+// WithDPoPMaxAge sets how far the 'iat' claim of a DPoP proof may be from the
+// current time, in either direction. Proofs are only protected against
+// replay for as long as the Cache of the Verifier keeps them, so this should
+// not be longer than the expiration of the Cache. Defaults to 1 minute.
+func WithDPoPMaxAge(maxAge time.Duration) DPoPOption {
+	return func(c *dpopConfig) {
+		c.maxAge = maxAge
+	}
+}
+
+// WithDPoPTargetURI sets the function that returns the URI that a request was
+// sent to, which the 'htu' claim of its DPoP proof must match. This is needed
+// when the server is behind a proxy that changes the scheme, host or path of
+// requests. By default, the URI is built from the Host header and the path of
+// the request, using https if the request was received over TLS.
+func WithDPoPTargetURI(targetURI func(r *http.Request) string) DPoPOption {
+	return func(c *dpopConfig) {
+		c.targetURI = targetURI
+	}
+}
+
+type dpopConfig struct {
+	maxAge    time.Duration
+	targetURI func(r *http.Request) string
+}
+
+func newDPoPConfig(opts []DPoPOption) *dpopConfig {
+	c := &dpopConfig{
+		maxAge:    defaultDPoPMaxAge,
+		targetURI: defaultDPoPTargetURI,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+func defaultDPoPTargetURI(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// VerifyDPoPProof verifies the DPoP proof in the DPoP header of the request,
+// as described in section 4.3 of RFC 9449, and that the given access token,
+// which must already have been verified, is bound to it. It verifies that:
+//
+//   - The request has exactly one DPoP header, which is a JWT with the
+//     'dpop+jwt' type, signed with an asymmetric algorithm by the public key
+//     in its 'jwk' header parameter.
+//   - The 'htm' and 'htu' claims match the method and URI of the request.
+//   - The 'iat' claim is within the maximum age set with WithDPoPMaxAge.
+//   - The 'ath' claim is the hash of the access token.
+//   - The 'cnf.jkt' claim of the access token is the JWK SHA-256 thumbprint of
+//     the public key.
+//   - The 'jti' claim has not been used before, according to the Cache of the
+//     Verifier, which is recorded atomically so that concurrent requests with
+//     the same proof cannot both succeed.
+//
+// If any of these fail, the error matches ErrInvalidDPoPProof. If the Cache
+// does not implement AddCache, such as NopCache, the proof cannot be protected
+// against replay, so it fails with ErrReplayProtectionUnsupported instead.
+func (j Verifier) VerifyDPoPProof(ctx context.Context, r *http.Request, token JWT, opts ...DPoPOption) error {
+	return j.verifyDPoPProof(ctx, r, token, newDPoPConfig(opts))
+}
+
+func (j Verifier) verifyDPoPProof(ctx context.Context, r *http.Request, token JWT, config *dpopConfig) error {
+	proofs := r.Header.Values(dpopHeader)
+	if len(proofs) != 1 {
+		return invalidDPoPProof("request must have exactly one DPoP header but has %d", len(proofs))
+	}
+
+	var thumbprint string
+	proof, err := jwt.Parse(
+		proofs[0],
+		func(proof *jwt.Token) (any, error) {
+			if typ, _ := proof.Header["typ"].(string); typ != dpopType {
+				return nil, fmt.Errorf("expected header 'typ' to be '%s'", dpopType)
+			}
+
+			key, jkt, err := parseDPoPKey(proof.Header["jwk"])
+			thumbprint = jkt
+			return key, err
+		},
+		jwt.WithValidMethods(dpopAlgorithms),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return invalidDPoPProof("parsing proof: %w", err)
+	}
+
+	claims, ok := proof.Claims.(jwt.MapClaims)
+	if !ok {
+		return invalidDPoPProof("parsed claims are not %T", claims)
+	}
+
+	if err = j.verifyDPoPClaims(claims, r, token, config); err != nil {
+		return err
+	}
+
+	jkt, err := lookupClaim(token.Claims, "cnf.jkt")
+	if err != nil {
+		return invalidDPoPProof("access token is not bound to a DPoP key: %w", err)
+	}
+	if jkt != thumbprint {
+		return invalidDPoPProof("access token is bound to a different DPoP key")
+	}
+
+	jti, _ := claims["jti"].(string)
+	added, err := j.markUsed(ctx, cacheKeyPrefixDPoP+thumbprint+":"+jti)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return invalidDPoPProof("proof has already been used")
+	}
+
+	return nil
+}
+
+func (j Verifier) verifyDPoPClaims(claims jwt.MapClaims, r *http.Request, token JWT, config *dpopConfig) error {
+	if jti, _ := claims["jti"].(string); jti == "" {
+		return invalidDPoPProof("claim 'jti' is required")
+	}
+
+	if htm, _ := claims["htm"].(string); htm != r.Method {
+		return invalidDPoPProof("claim 'htm' does not match the request method")
+	}
+
+	htu, _ := claims["htu"].(string)
+	if !sameTargetURI(htu, config.targetURI(r)) {
+		return invalidDPoPProof("claim 'htu' does not match the request URI")
+	}
+
+	iat, err := lookupTimestamp(claims, "iat")
+	if err != nil {
+		return invalidDPoPProof("%w", err)
+	}
+	if age := j.now().Sub(iat).Abs(); age > config.maxAge {
+		return invalidDPoPProof("claim 'iat' is more than %s from the current time", config.maxAge)
+	}
+
+	sum := sha256.Sum256([]byte(token.Raw))
+	if ath, _ := claims["ath"].(string); ath != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		return invalidDPoPProof("claim 'ath' does not match the access token")
+	}
+
+	return nil
+}
+
+// parseDPoPKey returns the public key in the 'jwk' header parameter of a DPoP
+// proof and its JWK SHA-256 thumbprint, as described in RFC 7638.
+func parseDPoPKey(header any) (any, string, error) {
+	if header == nil {
+		return nil, "", errors.New("header 'jwk' is required")
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, "", fmt.Errorf("json-encoding header 'jwk': %w", err)
+	}
+
+	var marshal jwkset.JWKMarshal
+	if err = json.Unmarshal(data, &marshal); err != nil {
+		return nil, "", fmt.Errorf("json-decoding header 'jwk': %w", err)
+	}
+
+	if marshal.D != "" || marshal.K != "" {
+		return nil, "", errors.New("header 'jwk' must not contain a private key")
+	}
+
+	jwk, err := jwkset.NewJWKFromMarshal(marshal, jwkset.JWKMarshalOptions{}, jwkset.JWKValidateOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing header 'jwk': %w", err)
+	}
+
+	thumbprint, err := jwkThumbprint(jwk.Marshal())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return jwk.Key(), thumbprint, nil
+}
+
+// jwkThumbprint returns the JWK SHA-256 thumbprint of a public key, which is
+// the hash of its required members in lexicographic order.
+func jwkThumbprint(jwk jwkset.JWKMarshal) (string, error) {
+	var members string
+	switch jwk.KTY {
+	case jwkset.KtyEC:
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk.CRV, jwk.KTY, jwk.X, jwk.Y)
+	case jwkset.KtyOKP:
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.CRV, jwk.KTY, jwk.X)
+	case jwkset.KtyRSA:
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.KTY, jwk.N)
+	default:
+		return "", fmt.Errorf("unsupported key type '%s'", jwk.KTY)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// sameTargetURI reports whether the 'htu' claim of a DPoP proof matches the
+// URI of the request, ignoring any query and fragment, as described in
+// section 4.3 of RFC 9449.
+func sameTargetURI(htu, target string) bool {
+	got, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+
+	want, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(got.Scheme, want.Scheme) &&
+		strings.EqualFold(got.Host, want.Host) &&
+		got.Path == want.Path
+}
+
+func invalidDPoPProof(format string, args ...any) error {
+	return &sentinelError{
+		sentinel: ErrInvalidDPoPProof,
+		err:      fmt.Errorf("invalid dpop proof: "+format, args...),
+	}
+}
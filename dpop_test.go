@@ -0,0 +1,396 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MicahParks/jwkset"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dpopClient holds the key that a client uses to sign DPoP proofs.
+type dpopClient struct {
+	key        *ecdsa.PrivateKey
+	jwk        map[string]any
+	thumbprint string
+}
+
+func newDPoPClient(t *testing.T) *dpopClient {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwk, err := jwkset.NewJWKFromKey(&key.PublicKey, jwkset.JWKOptions{})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(jwk.Marshal())
+	require.NoError(t, err)
+
+	var header map[string]any
+	require.NoError(t, json.Unmarshal(data, &header))
+
+	thumbprint, err := jwkThumbprint(jwk.Marshal())
+	require.NoError(t, err)
+
+	return &dpopClient{key: key, jwk: header, thumbprint: thumbprint}
+}
+
+// proof returns a DPoP proof for the given request and access token. Any
+// claims are added to the claims of the proof, replacing the defaults.
+func (c *dpopClient) proof(t *testing.T, method, uri, accessToken string, claims ...jwt.MapClaims) string {
+	t.Helper()
+
+	sum := sha256.Sum256([]byte(accessToken))
+
+	proofClaims := jwt.MapClaims{
+		"jti": base64.RawURLEncoding.EncodeToString([]byte(time.Now().String())),
+		"htm": method,
+		"htu": uri,
+		"iat": time.Now().Unix(),
+		"ath": base64.RawURLEncoding.EncodeToString(sum[:]),
+	}
+	for _, c := range claims {
+		for k, v := range c {
+			proofClaims[k] = v
+		}
+	}
+
+	proof := jwt.NewWithClaims(jwt.SigningMethodES256, proofClaims)
+	proof.Header["typ"] = "dpop+jwt"
+	proof.Header["jwk"] = c.jwk
+
+	signed, err := proof.SignedString(c.key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerifier_VerifyDPoPProof(t *testing.T) {
+	issuer := newTestIssuer(t)
+	client := newDPoPClient(t)
+	other := newDPoPClient(t)
+
+	const uri = "https://api.example.com/orders"
+
+	accessToken := issuer.sign(t, jwt.MapClaims{"sub": "00u1", "cnf": map[string]any{"jkt": client.thumbprint}})
+	unboundToken := issuer.sign(t, jwt.MapClaims{"sub": "00u1"})
+
+	cases := map[string]struct {
+		accessToken string
+		proofs      func(t *testing.T) []string
+		wantErr     string
+	}{
+		"valid": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, uri+"?page=2", accessToken)}
+			},
+		},
+		"no proof": {
+			accessToken: accessToken,
+			proofs:      func(*testing.T) []string { return nil },
+			wantErr:     "request must have exactly one DPoP header but has 0",
+		},
+		"multiple proofs": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				proof := client.proof(t, http.MethodPost, uri, accessToken)
+				return []string{proof, proof}
+			},
+			wantErr: "request must have exactly one DPoP header but has 2",
+		},
+		"wrong type": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				proof := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{})
+				proof.Header["jwk"] = client.jwk
+				signed, err := proof.SignedString(client.key)
+				require.NoError(t, err)
+				return []string{signed}
+			},
+			wantErr: "expected header 'typ' to be 'dpop+jwt'",
+		},
+		"signed with different key": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				proof := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{})
+				proof.Header["typ"] = "dpop+jwt"
+				proof.Header["jwk"] = client.jwk
+				signed, err := proof.SignedString(other.key)
+				require.NoError(t, err)
+				return []string{signed}
+			},
+			wantErr: "signature is invalid",
+		},
+		"symmetric algorithm": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				proof := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{})
+				proof.Header["typ"] = "dpop+jwt"
+				proof.Header["jwk"] = map[string]any{"kty": "oct", "k": "c2VjcmV0"}
+				signed, err := proof.SignedString([]byte("secret"))
+				require.NoError(t, err)
+				return []string{signed}
+			},
+			wantErr: "signing method HS256 is invalid",
+		},
+		"wrong method": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodGet, uri, accessToken)}
+			},
+			wantErr: "claim 'htm' does not match the request method",
+		},
+		"wrong uri": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, "https://api.example.com/users", accessToken)}
+			},
+			wantErr: "claim 'htu' does not match the request URI",
+		},
+		"stale": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, uri, accessToken, jwt.MapClaims{
+					"iat": time.Now().Add(-5 * time.Minute).Unix(),
+				})}
+			},
+			wantErr: "claim 'iat' is more than 1m0s from the current time",
+		},
+		"missing jti": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, uri, accessToken, jwt.MapClaims{"jti": ""})}
+			},
+			wantErr: "claim 'jti' is required",
+		},
+		"wrong access token hash": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, uri, unboundToken)}
+			},
+			wantErr: "claim 'ath' does not match the access token",
+		},
+		"unbound access token": {
+			accessToken: unboundToken,
+			proofs: func(t *testing.T) []string {
+				return []string{client.proof(t, http.MethodPost, uri, unboundToken)}
+			},
+			wantErr: "access token is not bound to a DPoP key",
+		},
+		"access token bound to different key": {
+			accessToken: accessToken,
+			proofs: func(t *testing.T) []string {
+				return []string{other.proof(t, http.MethodPost, uri, accessToken)}
+			},
+			wantErr: "access token is bound to a different DPoP key",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := issuer.verifier(WithCache(NewDefaultCache()))
+
+			token, err := v.ParseAndVerify(context.Background(), tt.accessToken)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, uri+"?page=2", nil)
+			for _, proof := range tt.proofs(t) {
+				req.Header.Add("DPoP", proof)
+			}
+
+			err = v.VerifyDPoPProof(context.Background(), req, token)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrInvalidDPoPProof)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The same proof must not be accepted twice.
+			err = v.VerifyDPoPProof(context.Background(), req, token)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "proof has already been used")
+		})
+	}
+}
+
+func TestVerifier_VerifyDPoPProof_replay(t *testing.T) {
+	issuer := newTestIssuer(t)
+	client := newDPoPClient(t)
+
+	accessToken := issuer.sign(t, jwt.MapClaims{"cnf": map[string]any{"jkt": client.thumbprint}})
+	proof := client.proof(t, http.MethodGet, "http://example.com/orders", accessToken)
+
+	cases := map[string]struct {
+		cache       Cache
+		wantSuccess int
+		wantErrIs   error
+	}{
+		"concurrent requests": {
+			cache:       NewDefaultCache(),
+			wantSuccess: 1,
+			wantErrIs:   ErrInvalidDPoPProof,
+		},
+		"cache without add": {
+			cache:     NewNopCache(),
+			wantErrIs: ErrReplayProtectionUnsupported,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := issuer.verifier(WithCache(tt.cache))
+
+			token, err := v.ParseAndVerify(context.Background(), accessToken)
+			require.NoError(t, err)
+
+			const requests = 20
+			errs := make([]error, requests)
+
+			var wg sync.WaitGroup
+			for i := range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					req := httptest.NewRequest(http.MethodGet, "http://example.com/orders", nil)
+					req.Header.Set("DPoP", proof)
+					errs[i] = v.VerifyDPoPProof(context.Background(), req, token)
+				}()
+			}
+			wg.Wait()
+
+			var success int
+			for _, err := range errs {
+				if err == nil {
+					success++
+					continue
+				}
+				assert.ErrorIs(t, err, tt.wantErrIs)
+			}
+			assert.Equal(t, tt.wantSuccess, success)
+		})
+	}
+}
+
+func TestVerifier_VerifyDPoPProof_clock(t *testing.T) {
+	issuer := newTestIssuer(t)
+	client := newDPoPClient(t)
+
+	accessToken := issuer.sign(t, jwt.MapClaims{"cnf": map[string]any{"jkt": client.thumbprint}})
+
+	v := issuer.verifier(WithCache(NewDefaultCache()))
+	token, err := v.ParseAndVerify(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	// The proof is only fresh according to the clock of the Verifier.
+	iat := time.Now().Add(-time.Hour)
+	v.now = func() time.Time { return iat }
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/orders", nil)
+	req.Header.Set("DPoP", client.proof(t, http.MethodGet, "http://example.com/orders", accessToken, jwt.MapClaims{
+		"iat": iat.Unix(),
+	}))
+
+	require.NoError(t, v.VerifyDPoPProof(context.Background(), req, token))
+}
+
+func TestVerifier_VerifyDPoPProof_targetURI(t *testing.T) {
+	issuer := newTestIssuer(t)
+	client := newDPoPClient(t)
+
+	accessToken := issuer.sign(t, jwt.MapClaims{"cnf": map[string]any{"jkt": client.thumbprint}})
+
+	v := issuer.verifier(WithCache(NewDefaultCache()))
+	token, err := v.ParseAndVerify(context.Background(), accessToken)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://10.0.0.1:8080/orders", nil)
+	req.Header.Set("DPoP", client.proof(t, http.MethodGet, "https://api.example.com/v1/orders", accessToken))
+
+	err = v.VerifyDPoPProof(context.Background(), req, token, WithDPoPTargetURI(func(r *http.Request) string {
+		return "https://api.example.com/v1" + r.URL.Path
+	}))
+	assert.NoError(t, err)
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// The example from section 3.1 of RFC 7638.
+	got, err := jwkThumbprint(jwkset.JWKMarshal{
+		KTY: jwkset.KtyRSA,
+		N:   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:   "AQAB",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", got)
+}
+
+func TestMiddleware_dpop(t *testing.T) {
+	issuer := newTestIssuer(t)
+	client := newDPoPClient(t)
+
+	accessToken := issuer.sign(t, jwt.MapClaims{"sub": "00u1", "cnf": map[string]any{"jkt": client.thumbprint}})
+
+	cases := map[string]struct {
+		authorization       string
+		proof               string
+		wantStatus          int
+		wantWWWAuthenticate string
+	}{
+		"valid": {
+			authorization: "DPoP " + accessToken,
+			proof:         client.proof(t, http.MethodGet, "http://example.com/orders", accessToken),
+			wantStatus:    http.StatusOK,
+		},
+		"bearer scheme": {
+			authorization:       "Bearer " + accessToken,
+			proof:               client.proof(t, http.MethodGet, "http://example.com/orders", accessToken),
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `DPoP realm="api", algs="ES256 ES384 ES512 RS256 RS384 RS512 PS256 PS384 PS512 EdDSA"`,
+		},
+		"invalid proof": {
+			authorization: "DPoP " + accessToken,
+			proof:         client.proof(t, http.MethodPost, "http://example.com/orders", accessToken),
+			wantStatus:    http.StatusUnauthorized,
+			wantWWWAuthenticate: `DPoP realm="api", error="invalid_dpop_proof", ` +
+				`error_description="The DPoP proof is invalid", ` +
+				`algs="ES256 ES384 ES512 RS256 RS384 RS512 PS256 PS384 PS512 EdDSA"`,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := issuer.verifier(WithCache(NewDefaultCache()))
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := v.Middleware(nil, WithRealm("api"), WithDPoP())(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			req.Header.Set("DPoP", tt.proof)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantWWWAuthenticate, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
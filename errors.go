@@ -0,0 +1,145 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrTokenExpired is returned by the expiration rules when the token is
+	// expired.
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenIssuedInFuture is returned by the issued at rules when the token
+	// was issued in the future.
+	ErrTokenIssuedInFuture = errors.New("token was issued in the future")
+	// ErrMissingClaim is returned when a rule requires a claim that is not
+	// present in the token.
+	ErrMissingClaim = errors.New("claim not found")
+	// ErrForbiddenClaim is returned when a rule forbids a claim that is
+	// present in the token.
+	ErrForbiddenClaim = errors.New("claim must not be present")
+	// ErrInsufficientScope is returned by the scope rules when a token does
+	// not have the scopes that are required. It corresponds to the
+	// insufficient_scope error code described in RFC 6750.
+	ErrInsufficientScope = errors.New("insufficient_scope")
+	// ErrForbiddenScope is returned by the rule created with WithNoScopesRule
+	// when a token has a scope that it must not have. Unlike
+	// ErrInsufficientScope, there is no RFC 6750 error code for it.
+	ErrForbiddenScope = errors.New("forbidden scope present")
+	// ErrInvalidSignature is returned when the signature of the token is not
+	// valid.
+	ErrInvalidSignature = errors.New("token signature is invalid")
+	// ErrKeyFetch is returned when the keys used to verify the signature of
+	// the token could not be fetched from the issuer. Unlike the other
+	// errors, this does not mean that the token is invalid, so it is usually
+	// appropriate to respond with a 503 status code.
+	ErrKeyFetch = errors.New("failed to fetch keys")
+	// ErrUnknownKID is returned when the token was signed with a key whose ID
+	// is not in the JSON Web Key Set of the issuer.
+	ErrUnknownKID = errors.New("unknown key id")
+	// ErrMalformed is returned when the token could not be parsed.
+	ErrMalformed = errors.New("token is malformed")
+	// ErrRuleFailed can be wrapped by the error of a [ContextRule] or a
+	// [ContextTokenRule] to report that the token failed the rule, as opposed
+	// to an error which prevented the rule from deciding, such as a failed
+	// lookup. See [Not] and [If].
+	ErrRuleFailed = errors.New("rule failed")
+)
+
+// errIndeterminate marks the failure of a rule which could not decide whether
+// the token is valid, such as when a lookup failed or the context was done.
+var errIndeterminate = errors.New("rule could not be verified")
+
+// ValidationError is returned by [Verifier.ParseAndVerify] when the token has
+// a valid signature but one or more of the rules failed. It contains a
+// ClaimFailure for each rule that failed.
+//
+// Each of the failures can be matched with errors.Is and errors.As, so for
+// instance errors.Is(err, ErrInsufficientScope) reports whether any of the
+// scope rules failed.
+type ValidationError struct {
+	Failures []ClaimFailure
+}
+
+// Error returns the errors of all of the failures, separated by semicolons.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		msgs = append(msgs, failure.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the failures as errors.
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for i := range e.Failures {
+		errs = append(errs, &e.Failures[i])
+	}
+	return errs
+}
+
+// ClaimFailure describes a rule that failed.
+type ClaimFailure struct {
+	// Key is the key of the claim that the rule verifies. It is empty for
+	// rules that verify the entire token, such as those created by
+	// [WithTokenRule] or [AllOf].
+	Key string
+	// Rule is the name of the rule, such as "exact_match" or "all_scopes".
+	// It is empty for rules that were not created by this package.
+	Rule string
+	// Reason describes why the rule failed.
+	Reason string
+	// Expected and Got are the expected and actual values of the claim, if
+	// the rule returned a [ValueError].
+	Expected any
+	Got      any
+	// Err is the error returned by the rule.
+	Err error
+
+	msg string
+}
+
+// Error returns a description of the failure that includes the key of the
+// claim.
+func (f *ClaimFailure) Error() string {
+	return f.msg
+}
+
+// Unwrap returns the error returned by the rule.
+func (f *ClaimFailure) Unwrap() error {
+	return f.Err
+}
+
+// ValueError can be returned by a [Rule] when the value of a claim is not the
+// expected value, so that the values are available on the [ClaimFailure].
+type ValueError struct {
+	Expected any
+	Got      any
+
+	msg string
+}
+
+// Error returns a description of the expected and actual values.
+func (e *ValueError) Error() string {
+	if e.msg != "" {
+		return e.msg
+	}
+	return fmt.Sprintf("expected '%v' but got '%v'", e.Expected, e.Got)
+}
+
+// sentinelError adds a sentinel error to an error without changing its
+// message.
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Unwrap() []error {
+	return []error{e.sentinel, e.err}
+}
@@ -0,0 +1,79 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidationError(t *testing.T) {
+	claims := map[string]any{
+		"sub": "Weidong",
+		"aud": []any{"Tashuan"},
+		"exp": float64(time.Now().Add(-time.Hour).Unix()),
+		"scp": []any{"openid"},
+		"act": map[string]any{"sub": "admin"},
+	}
+
+	rules := []ClaimRule{
+		WithCustomClaimExactMatchRule("sub", "Weidong"),
+		WithAudienceRule("foo"),
+		WithExpirationRule(0),
+		WithCustomClaimExactMatchRule("tenant.id", "acme"),
+		WithAllScopesRule("read"),
+		WithForbiddenClaimRule("act"),
+	}
+
+	verr := verifyRules(context.Background(), JWT{Claims: claims}, rules)
+	require.NotNil(t, verr)
+
+	var err error = verr
+	assert.EqualError(
+		t,
+		err,
+		"claim 'aud' is invalid: expected one of 'foo' but got 'Tashuan'; "+
+			"claim 'exp' is invalid: token is expired; "+
+			"claim 'tenant.id' not found: path not found at segment 'tenant'; "+
+			"claim 'scp' is invalid: missing scope(s): 'read'; "+
+			"claim 'act' must not be present",
+	)
+
+	for _, target := range []error{
+		ErrTokenExpired,
+		ErrMissingClaim,
+		ErrInsufficientScope,
+		ErrForbiddenClaim,
+	} {
+		assert.ErrorIs(t, err, target)
+	}
+	assert.False(t, errors.Is(err, ErrTokenIssuedInFuture))
+
+	var gotVerr *ValidationError
+	require.ErrorAs(t, err, &gotVerr)
+	require.Len(t, gotVerr.Failures, 5)
+
+	assert.Equal(t, "aud", gotVerr.Failures[0].Key)
+	assert.Equal(t, "audience", gotVerr.Failures[0].Rule)
+	assert.Equal(t, "expected one of 'foo' but got 'Tashuan'", gotVerr.Failures[0].Reason)
+	assert.Equal(t, []string{"foo"}, gotVerr.Failures[0].Expected)
+	assert.Equal(t, []string{"Tashuan"}, gotVerr.Failures[0].Got)
+
+	assert.Equal(t, "exp", gotVerr.Failures[1].Key)
+	assert.Equal(t, "expiration", gotVerr.Failures[1].Rule)
+	assert.ErrorIs(t, &gotVerr.Failures[1], ErrTokenExpired)
+
+	assert.Equal(t, "tenant.id", gotVerr.Failures[2].Key)
+	assert.Equal(t, "exact_match", gotVerr.Failures[2].Rule)
+	assert.Equal(t, "not found: path not found at segment 'tenant'", gotVerr.Failures[2].Reason)
+
+	var scopeErr *InsufficientScopeError
+	require.ErrorAs(t, &gotVerr.Failures[3], &scopeErr)
+	assert.Equal(t, []string{"read"}, scopeErr.Missing)
+
+	assert.Equal(t, "act", gotVerr.Failures[4].Key)
+	assert.Equal(t, "forbidden", gotVerr.Failures[4].Rule)
+}
@@ -0,0 +1,30 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExpiryContext returns a copy of ctx that is cancelled once the given JWT
+// expires, plus the grace period, which is useful for long-lived connections
+// such as WebSockets and server-sent event streams that are authenticated
+// once but can outlive the token.
+//
+// When the token expires, context.Cause of the returned context is
+// ErrTokenExpired, which distinguishes it from the cancellation of ctx. If the
+// token has already expired, the returned context is already cancelled.
+//
+// It returns an error if the token does not have a valid 'exp' claim. As with
+// context.WithDeadline, the returned CancelFunc must be called to release the
+// resources associated with the context.
+func ExpiryContext(ctx context.Context, token JWT, grace time.Duration) (context.Context, context.CancelFunc, error) {
+	exp, err := lookupTimestamp(token.Claims, "exp")
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting expiration time: %w", err)
+	}
+
+	ctx, cancel := context.WithDeadlineCause(ctx, exp.Add(grace), ErrTokenExpired)
+
+	return ctx, cancel, nil
+}
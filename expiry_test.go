@@ -0,0 +1,98 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryContext(t *testing.T) {
+	cases := map[string]struct {
+		claims        map[string]any
+		grace         time.Duration
+		wantCancelled bool
+		wantErr       string
+	}{
+		"not expired": {
+			claims: map[string]any{"exp": float64(time.Now().Add(time.Hour).Unix())},
+		},
+		"not expired/json number": {
+			claims: map[string]any{"exp": json.Number(
+				fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+			)},
+		},
+		"expired": {
+			claims:        map[string]any{"exp": float64(time.Now().Add(-time.Minute).Unix())},
+			wantCancelled: true,
+		},
+		"expired/within grace period": {
+			claims: map[string]any{"exp": float64(time.Now().Add(-time.Minute).Unix())},
+			grace:  time.Hour,
+		},
+		"missing exp": {
+			claims:  map[string]any{},
+			wantErr: "getting expiration time: claim 'exp' not found",
+		},
+		"invalid exp": {
+			claims:  map[string]any{"exp": "tomorrow"},
+			wantErr: "getting expiration time: claim 'exp' is invalid",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel, err := ExpiryContext(context.Background(), JWT{Claims: tt.claims}, tt.grace)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer cancel()
+
+			if tt.wantCancelled {
+				assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+				assert.ErrorIs(t, context.Cause(ctx), ErrTokenExpired)
+			} else {
+				assert.NoError(t, ctx.Err())
+			}
+		})
+	}
+}
+
+func TestExpiryContext_expires(t *testing.T) {
+	exp := time.Now().Add(time.Second).Truncate(time.Second)
+	token := JWT{Claims: map[string]any{"exp": float64(exp.Unix())}}
+
+	ctx, cancel, err := ExpiryContext(context.Background(), token, 0)
+	require.NoError(t, err)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+		assert.True(t, errors.Is(context.Cause(ctx), ErrTokenExpired))
+	case <-time.After(3 * time.Second):
+		t.Fatal("context was not cancelled when the token expired")
+	}
+}
+
+func TestExpiryContext_parentCancelled(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	token := JWT{Claims: map[string]any{"exp": float64(time.Now().Add(time.Hour).Unix())}}
+
+	ctx, cancel, err := ExpiryContext(parent, token, 0)
+	require.NoError(t, err)
+	defer cancel()
+
+	cancelParent()
+
+	<-ctx.Done()
+	assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+	assert.False(t, errors.Is(context.Cause(ctx), ErrTokenExpired))
+}
@@ -0,0 +1,204 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrMultipleTokens is returned when a request contains more than one token,
+// which section 2 of RFC 6750 does not allow. It matches ErrInvalidRequest.
+var ErrMultipleTokens = fmt.Errorf("%w: request contains more than one token", ErrInvalidRequest)
+
+const formContentType = "application/x-www-form-urlencoded"
+
+// TokenExtractor extracts a token from an HTTP request. ExtractToken returns
+// ErrNoToken if the request does not contain a token.
+type TokenExtractor interface {
+	ExtractToken(r *http.Request) (string, error)
+}
+
+// TokenExtractorFunc is an adapter to allow the use of an ordinary function
+// as a TokenExtractor.
+type TokenExtractorFunc func(r *http.Request) (string, error)
+
+// ExtractToken calls f(r).
+func (f TokenExtractorFunc) ExtractToken(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// HeaderExtractor extracts a token from a request header.
+type HeaderExtractor struct {
+	// Name is the name of the header.
+	Name string
+	// Scheme, if set, is the authentication scheme that must precede the
+	// token in the header, such as "Bearer". It is matched case-insensitively.
+	// If the header has a different scheme then the request is treated as if
+	// it does not contain a token.
+	Scheme string
+}
+
+// NewBearerExtractor creates a new HeaderExtractor which extracts a token
+// from the Authorization header with the Bearer scheme, as described in
+// section 2.1 of RFC 6750.
+func NewBearerExtractor() HeaderExtractor {
+	return NewHeaderExtractor("Authorization", "Bearer")
+}
+
+// NewHeaderExtractor creates a new HeaderExtractor. The scheme may be empty if
+// the header contains only the token, which is common with API gateways.
+func NewHeaderExtractor(name, scheme string) HeaderExtractor {
+	return HeaderExtractor{Name: name, Scheme: scheme}
+}
+
+// ExtractToken returns the token from the header.
+func (h HeaderExtractor) ExtractToken(r *http.Request) (string, error) {
+	values := r.Header.Values(h.Name)
+	if len(values) == 0 || values[0] == "" {
+		return "", ErrNoToken
+	}
+	if len(values) > 1 {
+		return "", ErrMultipleTokens
+	}
+
+	token := values[0]
+	if h.Scheme != "" {
+		scheme, rest, _ := strings.Cut(token, " ")
+		if !strings.EqualFold(scheme, h.Scheme) {
+			return "", ErrNoToken
+		}
+		token = rest
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("%w: %s header has no token", ErrInvalidRequest, h.Name)
+	}
+
+	return token, nil
+}
+
+// CookieExtractor extracts a token from a cookie.
+type CookieExtractor struct {
+	Name string
+}
+
+// NewCookieExtractor creates a new CookieExtractor for the cookie with the
+// given name.
+func NewCookieExtractor(name string) CookieExtractor {
+	return CookieExtractor{Name: name}
+}
+
+// ExtractToken returns the token from the cookie.
+func (c CookieExtractor) ExtractToken(r *http.Request) (string, error) {
+	values := make([]string, 0, 1)
+	for _, cookie := range r.Cookies() {
+		if cookie.Name == c.Name {
+			values = append(values, cookie.Value)
+		}
+	}
+
+	return singleValue(values)
+}
+
+// QueryExtractor extracts a token from a parameter in the URI query, as
+// described in section 2.3 of RFC 6750.
+type QueryExtractor struct {
+	Name string
+}
+
+// NewQueryExtractor creates a new QueryExtractor for the given query
+// parameter, which is usually "access_token".
+func NewQueryExtractor(name string) QueryExtractor {
+	return QueryExtractor{Name: name}
+}
+
+// ExtractToken returns the token from the query parameter.
+func (q QueryExtractor) ExtractToken(r *http.Request) (string, error) {
+	return singleValue(r.URL.Query()[q.Name])
+}
+
+// FormExtractor extracts a token from a parameter in a form-encoded request
+// body, as described in section 2.2 of RFC 6750.
+type FormExtractor struct {
+	Name string
+}
+
+// NewFormExtractor creates a new FormExtractor for the given form parameter,
+// which is usually "access_token".
+func NewFormExtractor(name string) FormExtractor {
+	return FormExtractor{Name: name}
+}
+
+// ExtractToken returns the token from the form parameter. Only requests
+// whose method is POST, PUT or PATCH and whose content type is
+// application/x-www-form-urlencoded, with any parameters such as a charset,
+// are considered to contain a form.
+func (f FormExtractor) ExtractToken(r *http.Request) (string, error) {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return "", ErrNoToken
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != formContentType {
+		return "", ErrNoToken
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return "", fmt.Errorf("%w: parsing form: %s", ErrInvalidRequest, err.Error())
+	}
+
+	return singleValue(r.PostForm[f.Name])
+}
+
+// ChainExtractor extracts a token using each of its extractors. Exactly one of
+// them must find a token, since section 2 of RFC 6750 does not allow a
+// request to contain more than one token.
+type ChainExtractor struct {
+	Extractors []TokenExtractor
+}
+
+// NewChainExtractor creates a new ChainExtractor with the given extractors.
+func NewChainExtractor(extractors ...TokenExtractor) ChainExtractor {
+	return ChainExtractor{Extractors: extractors}
+}
+
+// ExtractToken returns the token found by the extractors. It returns
+// ErrMultipleTokens if more than one of the extractors finds a token, and
+// returns any error from an extractor other than ErrNoToken.
+func (c ChainExtractor) ExtractToken(r *http.Request) (string, error) {
+	var found string
+	for _, extractor := range c.Extractors {
+		token, err := extractor.ExtractToken(r)
+		if errors.Is(err, ErrNoToken) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if found != "" {
+			return "", ErrMultipleTokens
+		}
+		found = token
+	}
+
+	if found == "" {
+		return "", ErrNoToken
+	}
+
+	return found, nil
+}
+
+func singleValue(values []string) (string, error) {
+	if len(values) == 0 || values[0] == "" {
+		return "", ErrNoToken
+	}
+	if len(values) > 1 {
+		return "", ErrMultipleTokens
+	}
+	return values[0], nil
+}
@@ -0,0 +1,189 @@
+package verifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenExtractors(t *testing.T) {
+	gatewayExtractor := TokenExtractorFunc(func(r *http.Request) (string, error) {
+		if token := r.Header.Get("X-Gateway-Token"); token != "" {
+			return token, nil
+		}
+		return "", ErrNoToken
+	})
+
+	chain := NewChainExtractor(
+		NewBearerExtractor(),
+		NewCookieExtractor("session"),
+		NewQueryExtractor("access_token"),
+		NewFormExtractor("access_token"),
+		gatewayExtractor,
+	)
+
+	cases := map[string]struct {
+		extractor TokenExtractor
+		newReq    func() *http.Request
+		wantToken string
+		wantErr   error
+	}{
+		"bearer/missing": {
+			extractor: NewBearerExtractor(),
+			newReq: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			wantErr: ErrNoToken,
+		},
+		"bearer/other scheme": {
+			extractor: NewBearerExtractor(),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Basic Zm9vOmJhcg==")
+				return req
+			},
+			wantErr: ErrNoToken,
+		},
+		"bearer/empty token": {
+			extractor: NewBearerExtractor(),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer  ")
+				return req
+			},
+			wantErr: ErrInvalidRequest,
+		},
+		"bearer/success": {
+			extractor: NewBearerExtractor(),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer foo")
+				return req
+			},
+			wantToken: "foo",
+		},
+		"header without scheme/success": {
+			extractor: NewHeaderExtractor("X-Access-Token", ""),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Access-Token", "foo")
+				return req
+			},
+			wantToken: "foo",
+		},
+		"cookie/multiple": {
+			extractor: NewCookieExtractor("session"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.AddCookie(&http.Cookie{Name: "session", Value: "foo"})
+				req.AddCookie(&http.Cookie{Name: "session", Value: "bar"})
+				return req
+			},
+			wantErr: ErrMultipleTokens,
+		},
+		"cookie/success": {
+			extractor: NewCookieExtractor("session"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.AddCookie(&http.Cookie{Name: "other", Value: "bar"})
+				req.AddCookie(&http.Cookie{Name: "session", Value: "foo"})
+				return req
+			},
+			wantToken: "foo",
+		},
+		"query/success": {
+			extractor: NewQueryExtractor("access_token"),
+			newReq: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/?access_token=foo", nil)
+			},
+			wantToken: "foo",
+		},
+		"form/get request": {
+			extractor: NewFormExtractor("access_token"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", strings.NewReader("access_token=foo"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantErr: ErrNoToken,
+		},
+		"form/success": {
+			extractor: NewFormExtractor("access_token"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("access_token=foo"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+			wantToken: "foo",
+		},
+		"form/charset": {
+			extractor: NewFormExtractor("access_token"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("access_token=foo"))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+				return req
+			},
+			wantToken: "foo",
+		},
+		"form/other content type": {
+			extractor: NewFormExtractor("access_token"),
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"access_token":"foo"}`))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			wantErr: ErrNoToken,
+		},
+		"chain/none": {
+			extractor: chain,
+			newReq: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			wantErr: ErrNoToken,
+		},
+		"chain/multiple locations": {
+			extractor: chain,
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/?access_token=foo", nil)
+				req.Header.Set("Authorization", "Bearer foo")
+				return req
+			},
+			wantErr: ErrMultipleTokens,
+		},
+		"chain/error from extractor": {
+			extractor: chain,
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer")
+				return req
+			},
+			wantErr: ErrInvalidRequest,
+		},
+		"chain/custom extractor": {
+			extractor: chain,
+			newReq: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("X-Gateway-Token", "foo")
+				return req
+			},
+			wantToken: "foo",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, err := tt.extractor.ExtractToken(tt.newReq())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package verifier
+
+import (
+	"context"
+	"crypto"
+	_ "crypto/sha256" // Registers SHA-256 and SHA-224 for crypto.Hash.
+	_ "crypto/sha512" // Registers SHA-384 and SHA-512 for crypto.Hash.
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseAndVerifyIDToken will parse the OIDC ID token and verify it as
+// described in section 3.1.3.7 of the OIDC Core specification, in addition
+// to verifying it using the provided rules. It always verifies that:
+//
+//   - The 'iss' claim equals the issuer of the Verifier.
+//   - The 'aud' claim contains the given client ID.
+//   - The 'azp' claim, if present, equals the given client ID, and is present
+//     if the 'aud' claim contains multiple audiences.
+//   - The 'sub' claim is present.
+//   - The token is not expired and was not issued in the future, allowing for
+//     the leeway set with WithLeeway.
+//
+// The checks that depend on the authentication request, such as those of the
+// 'nonce', 'at_hash', 'c_hash' and 'auth_time' claims, can be added with
+// WithNonceRule, WithAccessTokenHashRule, WithCodeHashRule and
+// Verifier.WithMaxAgeRule.
+func (j Verifier) ParseAndVerifyIDToken(
+	ctx context.Context,
+	token string,
+	clientID string,
+	rules ...ClaimRule,
+) (JWT, error) {
+	idTokenRules := []ClaimRule{
+		j.WithIssuerRule(),
+		WithAudienceRule(clientID),
+		WithAuthorizedPartyRule(clientID),
+		WithRequiredClaimRule("sub"),
+		j.WithExpirationRule(j.leeway),
+		j.WithIssuedAtRule(j.leeway),
+	}
+
+	return j.ParseAndVerify(ctx, token, append(idTokenRules, rules...)...)
+}
+
+// WithNonceRule will verify that the value of the 'nonce' claim equals the
+// nonce that was sent in the authentication request.
+func WithNonceRule(nonce string) ClaimRule {
+	rule := WithCustomClaimExactMatchRule("nonce", nonce)
+	rule.name = "nonce"
+	return rule
+}
+
+// WithAccessTokenHashRule will verify that the 'at_hash' claim of an ID token
+// matches the access token that was issued with it, using the hash algorithm
+// of the 'alg' header parameter of the ID token, as described in section
+// 3.2.2.9 of the OIDC Core specification. The 'at_hash' claim is required.
+func WithAccessTokenHashRule(accessToken string) ClaimRule {
+	return withTokenHashRule("at_hash", "access token", accessToken)
+}
+
+// WithCodeHashRule will verify that the 'c_hash' claim of an ID token matches
+// the authorization code that was issued with it, using the hash algorithm of
+// the 'alg' header parameter of the ID token, as described in section
+// 3.3.2.11 of the OIDC Core specification. The 'c_hash' claim is required.
+func WithCodeHashRule(code string) ClaimRule {
+	return withTokenHashRule("c_hash", "authorization code", code)
+}
+
+func withTokenHashRule(claim, description, value string) ClaimRule {
+	return WithTokenRule(claim, func(token JWT) error {
+		raw, err := lookupClaim(token.Claims, claim)
+		if err != nil {
+			return err
+		}
+
+		got, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("claim '%s' is invalid: expected a %T but got a %T", claim, got, raw)
+		}
+
+		alg, _ := token.Header["alg"].(string)
+
+		want, err := tokenHash(alg, value)
+		if err != nil {
+			return err
+		}
+
+		if got != want {
+			return fmt.Errorf("claim '%s' does not match the %s", claim, description)
+		}
+
+		return nil
+	})
+}
+
+// tokenHash returns the base64url encoding of the left-most half of the hash
+// of value, using the hash algorithm of the given JWS algorithm.
+func tokenHash(alg, value string) (string, error) {
+	var hash crypto.Hash
+	switch {
+	case alg == "EdDSA":
+		// Ed25519 uses SHA-512, which is the only curve that Okta supports.
+		hash = crypto.SHA512
+	case strings.HasSuffix(alg, "256"):
+		hash = crypto.SHA256
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	default:
+		return "", fmt.Errorf("unsupported algorithm '%s'", alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
+
+// WithMaxAgeRule will verify that the end-user authenticated no more than
+// maxAge ago, according to the 'auth_time' claim, which is needed when the
+// max_age parameter was sent in the authentication request. The 'auth_time'
+// claim may be either a number or a json.Number.
+func WithMaxAgeRule(maxAge time.Duration) ClaimRule {
+	return withMaxAgeRule(maxAge, time.Now)
+}
+
+// WithMaxAgeRule is the same as the package-level WithMaxAgeRule, except that
+// the age of the authentication is measured with the clock of the Verifier.
+func (j Verifier) WithMaxAgeRule(maxAge time.Duration) ClaimRule {
+	return withMaxAgeRule(maxAge, j.now)
+}
+
+func withMaxAgeRule(maxAge time.Duration, now func() time.Time) ClaimRule {
+	return WithTokenRule("max_age", func(token JWT) error {
+		authTime, err := lookupTimestamp(token.Claims, "auth_time")
+		if err != nil {
+			return err
+		}
+
+		if age := now().Sub(authTime); age > maxAge {
+			return fmt.Errorf("authentication at %s is older than %s", authTime.Format(time.RFC3339), maxAge)
+		}
+
+		return nil
+	})
+}
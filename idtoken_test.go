@@ -0,0 +1,226 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_ParseAndVerifyIDToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	const (
+		clientID    = "0oa1"
+		accessToken = "dNZX1hEZ9wBCzNL40Upu646bdzQA"
+		code        = "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk"
+	)
+
+	atHash, err := tokenHash("RS256", accessToken)
+	require.NoError(t, err)
+	cHash, err := tokenHash("RS256", code)
+	require.NoError(t, err)
+
+	validClaims := func() jwt.MapClaims {
+		now := time.Now()
+		return jwt.MapClaims{
+			"iss":       issuer.URL,
+			"sub":       "00u1",
+			"aud":       clientID,
+			"exp":       now.Add(time.Hour).Unix(),
+			"iat":       now.Unix(),
+			"auth_time": now.Add(-time.Minute).Unix(),
+			"nonce":     "n-0S6_WzA2Mj",
+			"at_hash":   atHash,
+			"c_hash":    cHash,
+		}
+	}
+
+	cases := map[string]struct {
+		modify  func(jwt.MapClaims)
+		opts    []Option
+		rules   []ClaimRule
+		wantErr string
+	}{
+		"valid": {
+			rules: []ClaimRule{
+				WithNonceRule("n-0S6_WzA2Mj"),
+				WithAccessTokenHashRule(accessToken),
+				WithCodeHashRule(code),
+				WithMaxAgeRule(5 * time.Minute),
+			},
+		},
+		"valid/multiple audiences with azp": {
+			modify: func(c jwt.MapClaims) {
+				c["aud"] = []any{clientID, "api"}
+				c["azp"] = clientID
+			},
+		},
+		"valid/issued in future within leeway": {
+			modify: func(c jwt.MapClaims) {
+				c["iat"] = time.Now().Add(time.Minute).Unix()
+			},
+			opts: []Option{WithLeeway(120)},
+		},
+		"wrong issuer": {
+			modify:  func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+			wantErr: "claim 'iss' is invalid",
+		},
+		"wrong audience": {
+			modify:  func(c jwt.MapClaims) { c["aud"] = "0oa2" },
+			wantErr: "claim 'aud' is invalid: expected '0oa1' but got '0oa2'",
+		},
+		"multiple audiences without azp": {
+			modify:  func(c jwt.MapClaims) { c["aud"] = []any{clientID, "api"} },
+			wantErr: "claim 'azp' is invalid: claim is required when 'aud' contains multiple audiences",
+		},
+		"missing sub": {
+			modify:  func(c jwt.MapClaims) { delete(c, "sub") },
+			wantErr: "claim 'sub' not found",
+		},
+		"expired": {
+			modify:  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: "claim 'exp' is invalid",
+		},
+		"issued in future": {
+			modify:  func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Minute).Unix() },
+			wantErr: "claim 'iat' is invalid",
+		},
+		"wrong nonce": {
+			rules:   []ClaimRule{WithNonceRule("other")},
+			wantErr: "claim 'nonce' is invalid: expected 'other' but got 'n-0S6_WzA2Mj'",
+		},
+		"wrong access token": {
+			rules:   []ClaimRule{WithAccessTokenHashRule("other")},
+			wantErr: "claim 'at_hash' does not match the access token",
+		},
+		"missing at_hash": {
+			modify:  func(c jwt.MapClaims) { delete(c, "at_hash") },
+			rules:   []ClaimRule{WithAccessTokenHashRule(accessToken)},
+			wantErr: "claim 'at_hash' not found",
+		},
+		"wrong code": {
+			rules:   []ClaimRule{WithCodeHashRule("other")},
+			wantErr: "claim 'c_hash' does not match the authorization code",
+		},
+		"authentication too old": {
+			modify:  func(c jwt.MapClaims) { c["auth_time"] = time.Now().Add(-time.Hour).Unix() },
+			rules:   []ClaimRule{WithMaxAgeRule(5 * time.Minute)},
+			wantErr: "is older than 5m0s",
+		},
+		"missing auth_time": {
+			modify:  func(c jwt.MapClaims) { delete(c, "auth_time") },
+			rules:   []ClaimRule{WithMaxAgeRule(5 * time.Minute)},
+			wantErr: "claim 'auth_time' not found",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			token, err := issuer.verifier(tt.opts...).ParseAndVerifyIDToken(
+				context.Background(),
+				issuer.sign(t, claims),
+				clientID,
+				tt.rules...,
+			)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "00u1", token.Claims["sub"])
+		})
+	}
+}
+
+func TestVerifier_WithMaxAgeRule(t *testing.T) {
+	now := time.Date(2021, time.September, 11, 14, 0, 0, 0, time.UTC)
+
+	v := New("https://example.okta.com/oauth2/default")
+	v.now = func() time.Time { return now }
+
+	cases := map[string]struct {
+		authTime time.Time
+		wantErr  string
+	}{
+		"recent": {
+			authTime: now.Add(-time.Minute),
+		},
+		"too old": {
+			authTime: now.Add(-10 * time.Minute),
+			wantErr:  "rule 'max_age' failed: authentication at 2021-09-11T13:50:00Z is older than 5m0s",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			token := JWT{Claims: map[string]any{"auth_time": float64(tt.authTime.Unix())}}
+
+			err := v.WithMaxAgeRule(5*time.Minute).verify(context.Background(), token)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestTokenHash(t *testing.T) {
+	cases := map[string]struct {
+		alg      string
+		value    string
+		wantHash string
+		wantLen  int
+		wantErr  string
+	}{
+		// The example from section A.3 of the OIDC Core specification.
+		"RS256": {
+			alg:      "RS256",
+			value:    "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			wantHash: "77QmUPtjPfzWtF2AnpK9RQ",
+		},
+		"ES384": {
+			alg:     "ES384",
+			value:   "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			wantLen: 32,
+		},
+		"EdDSA": {
+			alg:     "EdDSA",
+			value:   "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y",
+			wantLen: 43,
+		},
+		"unsupported algorithm": {
+			alg:     "none",
+			wantErr: "unsupported algorithm 'none'",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tokenHash(tt.alg, tt.value)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.wantHash != "" {
+				assert.Equal(t, tt.wantHash, got)
+			}
+			if tt.wantLen != 0 {
+				assert.Len(t, got, tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package verifier
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidIssuer is returned by ValidateIssuer and NewValidated when the
+	// issuer is not a valid Okta issuer URL.
+	ErrInvalidIssuer = errors.New("invalid issuer")
+	// ErrOrgAuthorizationServer is returned when a Verifier created with
+	// WithResourceServer is configured with the issuer of the Okta org
+	// authorization server, whose access tokens are only intended to be used
+	// with Okta's own APIs and should not be verified by other resource
+	// servers.
+	ErrOrgAuthorizationServer = errors.New("issuer is the org authorization server")
+)
+
+// WithResourceServer marks the Verifier as being used by a resource server,
+// which verifies access tokens for its own APIs. Since the access tokens of
+// the Okta org authorization server are only intended for Okta's own APIs,
+// [Verifier.ParseAndVerify] then fails with ErrOrgAuthorizationServer if the
+// issuer is that of the org authorization server, as reported by
+// IsOrgAuthorizationServer, instead of accepting its tokens.
+func WithResourceServer() Option {
+	return func(j *Verifier) {
+		j.resourceServer = true
+	}
+}
+
+// WithIssuerAliases sets other issuers that identify the same authorization
+// server as the issuer of the Verifier, such as when an Okta org is reachable
+// both at its okta.com domain and at a custom URL domain, in which case tokens
+// have the issuer of the domain that the client used.
+//
+// The keys of a token whose 'iss' claim is one of the aliases are fetched
+// through discovery against the alias, and are cached separately from those
+// of the issuer. [Verifier.WithIssuerRule] accepts the issuer or any of its
+// aliases, so the same rules can be used for all of them.
+func WithIssuerAliases(aliases ...string) Option {
+	return func(j *Verifier) {
+		j.issuerAliases = append(j.issuerAliases, aliases...)
+	}
+}
+
+// NewValidated creates a new Verifier like New, but first validates the issuer
+// and any aliases set with WithIssuerAliases with ValidateIssuer, and if
+// WithResourceServer is used, verifies that none of them is the org
+// authorization server. This catches common mistakes in the configuration of
+// the issuer, such as a trailing slash, when the Verifier is created rather
+// than when the first token fails to verify.
+func NewValidated(issuer string, opts ...Option) (Verifier, error) {
+	v := New(issuer, opts...)
+
+	for _, iss := range v.issuers() {
+		if err := ValidateIssuer(iss); err != nil {
+			return Verifier{}, err
+		}
+	}
+	if err := v.checkResourceServer(); err != nil {
+		return Verifier{}, err
+	}
+
+	return v, nil
+}
+
+// IsOrgAuthorizationServer reports whether the issuer is that of an Okta org
+// authorization server, such as https://example.okta.com, rather than that of
+// a custom authorization server, such as
+// https://example.okta.com/oauth2/default, whose path is /oauth2/ followed by
+// the ID of the authorization server.
+func IsOrgAuthorizationServer(issuer string) bool {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	return strings.Trim(u.Path, "/") == ""
+}
+
+// ValidateIssuer reports the common mistakes in an Okta issuer URL, which
+// would otherwise cause every token to fail the issuer rule or the keys to
+// fail to be fetched. The issuer must:
+//
+//   - Be an absolute URL that uses https, except for loopback hosts, which are
+//     allowed to use http for local testing.
+//   - Not have a trailing slash, a query or a fragment.
+//   - Have either an empty path, for the org authorization server, or a path
+//     of /oauth2/ followed by the ID of a custom authorization server, such as
+//     /oauth2/default, and not the path of an endpoint such as
+//     /oauth2/default/v1/keys or /.well-known/openid-configuration.
+//
+// All of the problems are described by the error, which matches
+// ErrInvalidIssuer.
+func ValidateIssuer(issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return invalidIssuer(issuer, "it is not a valid URL")
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return invalidIssuer(issuer, "it must be an absolute URL, such as https://example.okta.com/oauth2/default")
+	}
+
+	problems := issuerURLProblems(u)
+	if problem := issuerPathProblem(u.Path); problem != "" {
+		problems = append(problems, problem)
+	}
+
+	if len(problems) != 0 {
+		return invalidIssuer(issuer, problems...)
+	}
+
+	return nil
+}
+
+// issuerURLProblems returns the problems with the scheme, the query and the
+// fragment of an issuer URL and whether its path has a trailing slash.
+func issuerURLProblems(u *url.URL) []string {
+	var problems []string
+
+	if u.Scheme != "https" && !isLoopback(u.Hostname()) {
+		problems = append(problems, "it must use https")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		problems = append(problems, "it must not have a query or a fragment")
+	}
+	if strings.HasSuffix(u.Path, "/") {
+		problems = append(problems, "it must not have a trailing slash")
+	}
+
+	return problems
+}
+
+// issuerPathProblem returns the problem with the path of an issuer URL, or an
+// empty string if it is the path of an Okta authorization server.
+func issuerPathProblem(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	switch {
+	case segments[0] == "":
+		return ""
+	case strings.Contains(p, "/.well-known/"):
+		return "it must not include the path of the discovery endpoint"
+	case segments[0] != "oauth2":
+		return "its path must be empty or /oauth2/ followed by the ID of an authorization server"
+	case len(segments) == 1:
+		return "it is missing the ID of the authorization server, such as /oauth2/default"
+	case segments[1] == "v1" || len(segments) > 2:
+		return "it must not include the path of an endpoint, such as /v1/keys"
+	default:
+		return ""
+	}
+}
+
+func invalidIssuer(issuer string, problems ...string) error {
+	return &sentinelError{
+		sentinel: ErrInvalidIssuer,
+		err:      fmt.Errorf("invalid issuer '%s': %s", issuer, strings.Join(problems, "; ")),
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checkResourceServer returns an error if the Verifier is used by a resource
+// server but its issuer, or any of its aliases, is the org authorization
+// server.
+func (j Verifier) checkResourceServer() error {
+	if !j.resourceServer {
+		return nil
+	}
+
+	issuers := j.issuers()
+	i := slices.IndexFunc(issuers, IsOrgAuthorizationServer)
+	if i == -1 {
+		return nil
+	}
+	issuer := issuers[i]
+
+	return &sentinelError{
+		sentinel: ErrOrgAuthorizationServer,
+		err: fmt.Errorf(
+			"issuer '%s' is the org authorization server, whose access tokens are only intended for Okta's "+
+				"own APIs; use a custom authorization server, such as '%s/oauth2/default', to issue access "+
+				"tokens for resource servers",
+			issuer,
+			strings.TrimSuffix(issuer, "/"),
+		),
+	}
+}
+
+// issuers returns the issuer of the Verifier followed by its aliases.
+func (j Verifier) issuers() []string {
+	return append([]string{j.issuer}, j.issuerAliases...)
+}
+
+// keysIssuer returns the issuer whose keys should verify the token, which is
+// the alias set with WithIssuerAliases that equals the unverified 'iss' claim
+// of the token, or otherwise the issuer of the Verifier.
+func (j Verifier) keysIssuer(token string) string {
+	if len(j.issuerAliases) == 0 {
+		return j.issuer
+	}
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return j.issuer
+	}
+
+	if iss, ok := claims["iss"].(string); ok && slices.Contains(j.issuerAliases, iss) {
+		return iss
+	}
+
+	return j.issuer
+}
+
+// withIssuersRule will verify that the value of the 'iss' claim is one of the
+// accepted issuers.
+func withIssuersRule(accepted []string) ClaimRule {
+	return ClaimRule{
+		Key:  "iss",
+		name: "issuer",
+		Rule: func(value any) error {
+			got, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("expected a %T but got a %T", got, value)
+			}
+
+			if slices.Contains(accepted, got) {
+				return nil
+			}
+
+			return &ValueError{
+				Expected: accepted,
+				Got:      got,
+				msg:      fmt.Sprintf("expected one of %s but got '%s'", quoteJoin(accepted), got),
+			}
+		},
+	}
+}
+
+// issuerMismatch returns the error of the issuer rule when the 'iss' claim of
+// a token does not equal the expected issuer, which explains the difference
+// if it is a common mistake in the configuration of the issuer.
+func issuerMismatch(want, got string) error {
+	err := &ValueError{Expected: want, Got: got}
+
+	var hint string
+	switch wantServer, gotServer, sameHost := authorizationServers(want, got); {
+	case strings.TrimSuffix(want, "/") == strings.TrimSuffix(got, "/"):
+		hint = "the issuers differ only by a trailing slash"
+	case !sameHost:
+	case wantServer == gotServer:
+	case gotServer == "":
+		hint = "the token was issued by the org authorization server"
+	case wantServer == "":
+		hint = fmt.Sprintf("the token was issued by the custom authorization server '%s' "+
+			"but the org authorization server is expected", gotServer)
+	default:
+		hint = fmt.Sprintf("the token was issued by the authorization server '%s' instead of '%s'",
+			gotServer, wantServer)
+	}
+
+	if hint != "" {
+		err.msg = fmt.Sprintf("expected '%s' but got '%s': %s", want, got, hint)
+	}
+
+	return err
+}
+
+// authorizationServers returns the IDs of the authorization servers of the
+// given issuers, which are empty for the org authorization server, and
+// whether the issuers are Okta issuers on the same host.
+func authorizationServers(a, b string) (string, string, bool) {
+	aHost, aServer, aOK := authorizationServer(a)
+	bHost, bServer, bOK := authorizationServer(b)
+
+	return aServer, bServer, aOK && bOK && aHost == bHost
+}
+
+// authorizationServer returns the host and authorization server ID of an Okta
+// issuer, and whether the issuer is of that form.
+func authorizationServer(issuer string) (string, string, bool) {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case segments[0] == "":
+		return u.Host, "", true
+	case len(segments) == 2 && segments[0] == "oauth2":
+		return u.Host, segments[1], true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,275 @@
+package verifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateIssuer(t *testing.T) {
+	cases := map[string]struct {
+		issuer  string
+		wantErr string
+	}{
+		"custom authorization server": {
+			issuer: "https://example.okta.com/oauth2/aus1a2b3c4d5e6f7g8h9",
+		},
+		"default authorization server": {
+			issuer: "https://example.okta.com/oauth2/default",
+		},
+		"org authorization server": {
+			issuer: "https://example.okta.com",
+		},
+		"loopback over http": {
+			issuer: "http://127.0.0.1:8080/oauth2/default",
+		},
+		"trailing slash": {
+			issuer:  "https://example.okta.com/oauth2/default/",
+			wantErr: "it must not have a trailing slash",
+		},
+		"org authorization server/trailing slash": {
+			issuer:  "https://example.okta.com/",
+			wantErr: "it must not have a trailing slash",
+		},
+		"http": {
+			issuer:  "http://example.okta.com/oauth2/default",
+			wantErr: "it must use https",
+		},
+		"http and trailing slash": {
+			issuer:  "http://example.okta.com/oauth2/default/",
+			wantErr: "it must use https; it must not have a trailing slash",
+		},
+		"relative": {
+			issuer:  "example.okta.com/oauth2/default",
+			wantErr: "it must be an absolute URL",
+		},
+		"query": {
+			issuer:  "https://example.okta.com/oauth2/default?foo=bar",
+			wantErr: "it must not have a query or a fragment",
+		},
+		"missing authorization server id": {
+			issuer:  "https://example.okta.com/oauth2",
+			wantErr: "it is missing the ID of the authorization server",
+		},
+		"keys endpoint": {
+			issuer:  "https://example.okta.com/oauth2/default/v1/keys",
+			wantErr: "it must not include the path of an endpoint",
+		},
+		"org keys endpoint": {
+			issuer:  "https://example.okta.com/oauth2/v1/keys",
+			wantErr: "it must not include the path of an endpoint",
+		},
+		"discovery endpoint": {
+			issuer:  "https://example.okta.com/oauth2/default/.well-known/openid-configuration",
+			wantErr: "it must not include the path of the discovery endpoint",
+		},
+		"other path": {
+			issuer:  "https://example.okta.com/api/v1",
+			wantErr: "its path must be empty or /oauth2/ followed by the ID of an authorization server",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateIssuer(tt.issuer)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrInvalidIssuer)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestIsOrgAuthorizationServer(t *testing.T) {
+	cases := map[string]struct {
+		issuer string
+		want   bool
+	}{
+		"org authorization server":               {issuer: "https://example.okta.com", want: true},
+		"org authorization server/custom domain": {issuer: "https://login.example.com", want: true},
+		"default authorization server":           {issuer: "https://example.okta.com/oauth2/default"},
+		"custom authorization server":            {issuer: "https://example.okta.com/oauth2/aus1"},
+		"not a url":                              {issuer: "example"},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsOrgAuthorizationServer(tt.issuer))
+		})
+	}
+}
+
+func TestNewValidated(t *testing.T) {
+	cases := map[string]struct {
+		issuer    string
+		opts      []Option
+		wantErrIs error
+	}{
+		"valid": {
+			issuer: "https://example.okta.com/oauth2/default",
+			opts:   []Option{WithResourceServer()},
+		},
+		"org authorization server": {
+			issuer: "https://example.okta.com",
+		},
+		"org authorization server/resource server": {
+			issuer:    "https://example.okta.com",
+			opts:      []Option{WithResourceServer()},
+			wantErrIs: ErrOrgAuthorizationServer,
+		},
+		"invalid issuer": {
+			issuer:    "https://example.okta.com/oauth2/default/",
+			wantErrIs: ErrInvalidIssuer,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewValidated(tt.issuer, tt.opts...)
+			if tt.wantErrIs != nil {
+				assert.ErrorIs(t, err, tt.wantErrIs)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWithResourceServer(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	_, err := issuer.verifier(WithResourceServer()).ParseAndVerify(
+		context.Background(),
+		issuer.sign(t, jwt.MapClaims{"iss": issuer.URL}),
+	)
+	require.ErrorIs(t, err, ErrOrgAuthorizationServer)
+	assert.Contains(t, err.Error(), "use a custom authorization server, such as '"+issuer.URL+"/oauth2/default'")
+}
+
+func TestWithIssuerRule_hints(t *testing.T) {
+	cases := map[string]struct {
+		want    string
+		got     string
+		wantErr string
+	}{
+		"trailing slash": {
+			want:    "https://example.okta.com/oauth2/default/",
+			got:     "https://example.okta.com/oauth2/default",
+			wantErr: "claim 'iss' is invalid: expected 'https://example.okta.com/oauth2/default/' but got 'https://example.okta.com/oauth2/default': the issuers differ only by a trailing slash",
+		},
+		"other authorization server": {
+			want:    "https://example.okta.com/oauth2/default",
+			got:     "https://example.okta.com/oauth2/aus1",
+			wantErr: "the token was issued by the authorization server 'aus1' instead of 'default'",
+		},
+		"org authorization server": {
+			want:    "https://example.okta.com/oauth2/default",
+			got:     "https://example.okta.com",
+			wantErr: "the token was issued by the org authorization server",
+		},
+		"custom authorization server": {
+			want:    "https://example.okta.com",
+			got:     "https://example.okta.com/oauth2/default",
+			wantErr: "the token was issued by the custom authorization server 'default' but the org authorization server is expected",
+		},
+		"other host": {
+			want:    "https://example.okta.com/oauth2/default",
+			got:     "https://other.okta.com/oauth2/default",
+			wantErr: "claim 'iss' is invalid: expected 'https://example.okta.com/oauth2/default' but got 'https://other.okta.com/oauth2/default'",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithIssuerRule(tt.want).verify(context.Background(), JWT{Claims: map[string]any{"iss": tt.got}})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestWithIssuerAliases(t *testing.T) {
+	canonical := newTestIssuer(t)
+	alias := newTestIssuer(t)
+	other := newTestIssuer(t)
+
+	cases := map[string]struct {
+		signer     *testIssuer
+		iss        string
+		wantIssuer string
+		wantErr    string
+		wantErrIs  error
+	}{
+		"canonical issuer": {
+			signer:     canonical,
+			iss:        canonical.URL,
+			wantIssuer: canonical.URL,
+		},
+		"alias": {
+			signer:     alias,
+			iss:        alias.URL,
+			wantIssuer: alias.URL,
+		},
+		"alias signed by canonical issuer": {
+			signer:    canonical,
+			iss:       alias.URL,
+			wantErrIs: ErrInvalidSignature,
+		},
+		"unknown issuer": {
+			signer:    other,
+			iss:       other.URL,
+			wantErrIs: ErrInvalidSignature,
+		},
+		"unknown issuer signed by canonical issuer": {
+			signer:  canonical,
+			iss:     other.URL,
+			wantErr: "claim 'iss' is invalid: expected one of '" + canonical.URL + "', '" + alias.URL + "' but got",
+		},
+	}
+
+	v := New(canonical.URL, WithIssuerAliases(alias.URL))
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, err := v.ParseAndVerify(
+				context.Background(),
+				tt.signer.sign(t, jwt.MapClaims{"iss": tt.iss}),
+				v.WithIssuerRule(),
+			)
+			if tt.wantErr != "" || tt.wantErrIs != nil {
+				require.Error(t, err)
+				if tt.wantErr != "" {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIssuer, token.Verification.Issuer)
+		})
+	}
+}
+
+func TestNewValidated_aliases(t *testing.T) {
+	_, err := NewValidated(
+		"https://example.okta.com/oauth2/default",
+		WithIssuerAliases("https://login.example.com/oauth2/default/"),
+	)
+	require.ErrorIs(t, err, ErrInvalidIssuer)
+	assert.Contains(t, err.Error(), "invalid issuer 'https://login.example.com/oauth2/default/'")
+
+	_, err = NewValidated(
+		"https://example.okta.com/oauth2/default",
+		WithIssuerAliases("https://login.example.com"),
+		WithResourceServer(),
+	)
+	assert.ErrorIs(t, err, ErrOrgAuthorizationServer)
+}
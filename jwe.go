@@ -0,0 +1,150 @@
+package verifier
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var (
+	// ErrDecryption is returned when an encrypted token cannot be decrypted,
+	// such as when none of the decryption keys can decrypt it.
+	ErrDecryption = errors.New("token could not be decrypted")
+	// ErrEncryptionRequired is returned when a token is not encrypted but
+	// WithRequiredEncryption is used.
+	ErrEncryptionRequired = errors.New("token is not encrypted")
+)
+
+// defaultKeyEncryptionAlgorithms are the algorithms that the content
+// encryption key of an encrypted token may be encrypted with by default.
+var defaultKeyEncryptionAlgorithms = []string{
+	string(jose.RSA_OAEP),
+	string(jose.RSA_OAEP_256),
+	string(jose.ECDH_ES),
+	string(jose.ECDH_ES_A128KW),
+	string(jose.ECDH_ES_A192KW),
+	string(jose.ECDH_ES_A256KW),
+}
+
+// defaultContentEncryptionAlgorithms are the algorithms that an encrypted
+// token may be encrypted with by default.
+var defaultContentEncryptionAlgorithms = []string{
+	string(jose.A128GCM),
+	string(jose.A192GCM),
+	string(jose.A256GCM),
+	string(jose.A128CBC_HS256),
+	string(jose.A192CBC_HS384),
+	string(jose.A256CBC_HS512),
+}
+
+// WithDecryptionKey adds a private key that is used to decrypt encrypted
+// tokens, which must be nested JWTs, such as encrypted ID tokens. The token
+// is decrypted and the JWT inside of it is then verified in the same way as
+// an unencrypted token. The key must be an *rsa.PrivateKey for the RSA-OAEP
+// algorithms or an *ecdsa.PrivateKey for the ECDH-ES algorithms.
+//
+// If kid is not empty, the key is only used for tokens whose 'kid' header
+// parameter equals it. Otherwise it is tried for all encrypted tokens.
+func WithDecryptionKey(kid string, key crypto.PrivateKey) Option {
+	return func(j *Verifier) {
+		j.decryptionKeys = append(j.decryptionKeys, decryptionKey{kid: kid, key: key})
+	}
+}
+
+// WithEncryptionAlgorithms sets the key encryption algorithms, such as
+// "RSA-OAEP-256" and "ECDH-ES", and the content encryption algorithms, such as
+// "A256GCM", that encrypted tokens may use. Tokens encrypted with any other
+// algorithms are rejected. If either is empty, it defaults to the RSA-OAEP and
+// ECDH-ES key encryption algorithms or all of the content encryption
+// algorithms of RFC 7518, respectively.
+func WithEncryptionAlgorithms(keyAlgorithms, contentAlgorithms []string) Option {
+	return func(j *Verifier) {
+		j.keyEncryptionAlgorithms = keyAlgorithms
+		j.contentEncryptionAlgorithms = contentAlgorithms
+	}
+}
+
+// WithRequiredEncryption causes tokens that are not encrypted to be rejected
+// with ErrEncryptionRequired.
+func WithRequiredEncryption() Option {
+	return func(j *Verifier) {
+		j.requireEncryption = true
+	}
+}
+
+type decryptionKey struct {
+	kid string
+	key crypto.PrivateKey
+}
+
+// isEncrypted reports whether the token uses the JWE compact serialization,
+// which has five parts, rather than the JWS compact serialization, which has
+// three.
+func isEncrypted(token string) bool {
+	return strings.Count(token, ".") == 4
+}
+
+// encryptionAlgorithms returns the key and content encryption algorithms that
+// encrypted tokens may use, which default to defaultKeyEncryptionAlgorithms
+// and defaultContentEncryptionAlgorithms.
+func (j Verifier) encryptionAlgorithms() ([]jose.KeyAlgorithm, []jose.ContentEncryption) {
+	keyAlgs, contentAlgs := j.keyEncryptionAlgorithms, j.contentEncryptionAlgorithms
+	if len(keyAlgs) == 0 {
+		keyAlgs = defaultKeyEncryptionAlgorithms
+	}
+	if len(contentAlgs) == 0 {
+		contentAlgs = defaultContentEncryptionAlgorithms
+	}
+
+	keyAlgorithms := make([]jose.KeyAlgorithm, 0, len(keyAlgs))
+	for _, alg := range keyAlgs {
+		keyAlgorithms = append(keyAlgorithms, jose.KeyAlgorithm(alg))
+	}
+
+	contentAlgorithms := make([]jose.ContentEncryption, 0, len(contentAlgs))
+	for _, enc := range contentAlgs {
+		contentAlgorithms = append(contentAlgorithms, jose.ContentEncryption(enc))
+	}
+
+	return keyAlgorithms, contentAlgorithms
+}
+
+// decrypt decrypts the encrypted token and returns the JWT inside of it.
+func (j Verifier) decrypt(token string) (string, error) {
+	keyAlgorithms, contentAlgorithms := j.encryptionAlgorithms()
+
+	encrypted, err := jose.ParseEncryptedCompact(token, keyAlgorithms, contentAlgorithms)
+	if err != nil {
+		return "", &sentinelError{sentinel: ErrDecryption, err: fmt.Errorf("parsing jwe: %w", err)}
+	}
+
+	var errs []error
+	for _, key := range j.decryptionKeys {
+		if key.kid != "" && key.kid != encrypted.Header.KeyID {
+			continue
+		}
+
+		plaintext, err := encrypted.Decrypt(key.key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		return string(plaintext), nil
+	}
+
+	if len(errs) == 0 {
+		return "", &sentinelError{
+			sentinel: ErrDecryption,
+			err:      fmt.Errorf("no decryption key for kid '%s'", encrypted.Header.KeyID),
+		}
+	}
+
+	return "", &sentinelError{
+		sentinel: ErrDecryption,
+		err:      fmt.Errorf("decrypting jwe: %w", errors.Join(errs...)),
+	}
+}
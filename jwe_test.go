@@ -0,0 +1,140 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encrypt returns the token encrypted for the given recipient as a nested
+// JWT.
+func encrypt(t *testing.T, token string, recipient jose.Recipient, enc jose.ContentEncryption) string {
+	t.Helper()
+
+	encrypter, err := jose.NewEncrypter(enc, recipient, (&jose.EncrypterOptions{}).WithContentType("JWT"))
+	require.NoError(t, err)
+
+	encrypted, err := encrypter.Encrypt([]byte(token))
+	require.NoError(t, err)
+
+	serialized, err := encrypted.CompactSerialize()
+	require.NoError(t, err)
+
+	return serialized
+}
+
+func TestVerifier_ParseAndVerify_encrypted(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	signed := issuer.sign(t, jwt.MapClaims{"sub": "00u1"})
+
+	cases := map[string]struct {
+		token         string
+		opts          []Option
+		wantEncrypted bool
+		wantErrIs     error
+		wantErr       string
+	}{
+		"RSA-OAEP-256": {
+			token: encrypt(t, signed, jose.Recipient{
+				Algorithm: jose.RSA_OAEP_256,
+				Key:       &rsaKey.PublicKey,
+				KeyID:     "enc-rsa",
+			}, jose.A256GCM),
+			opts: []Option{
+				WithDecryptionKey("enc-ec", ecKey),
+				WithDecryptionKey("enc-rsa", rsaKey),
+			},
+			wantEncrypted: true,
+		},
+		"ECDH-ES": {
+			token: encrypt(t, signed, jose.Recipient{
+				Algorithm: jose.ECDH_ES,
+				Key:       &ecKey.PublicKey,
+			}, jose.A128CBC_HS256),
+			opts:          []Option{WithDecryptionKey("", ecKey)},
+			wantEncrypted: true,
+		},
+		"unencrypted": {
+			token: signed,
+			opts:  []Option{WithDecryptionKey("", rsaKey)},
+		},
+		"unencrypted/encryption required": {
+			token:     signed,
+			opts:      []Option{WithDecryptionKey("", rsaKey), WithRequiredEncryption()},
+			wantErrIs: ErrEncryptionRequired,
+			wantErr:   "token is not encrypted",
+		},
+		"no decryption keys": {
+			token: encrypt(t, signed, jose.Recipient{
+				Algorithm: jose.RSA_OAEP_256,
+				Key:       &rsaKey.PublicKey,
+				KeyID:     "enc-rsa",
+			}, jose.A256GCM),
+			wantErrIs: ErrDecryption,
+			wantErr:   "no decryption key for kid 'enc-rsa'",
+		},
+		"wrong decryption key": {
+			token: encrypt(t, signed, jose.Recipient{
+				Algorithm: jose.RSA_OAEP_256,
+				Key:       &rsaKey.PublicKey,
+			}, jose.A256GCM),
+			opts:      []Option{WithDecryptionKey("", otherKey)},
+			wantErrIs: ErrDecryption,
+			wantErr:   "decrypting jwe",
+		},
+		"disallowed algorithm": {
+			token: encrypt(t, signed, jose.Recipient{
+				Algorithm: jose.RSA_OAEP,
+				Key:       &rsaKey.PublicKey,
+			}, jose.A256GCM),
+			opts: []Option{
+				WithDecryptionKey("", rsaKey),
+				WithEncryptionAlgorithms([]string{"RSA-OAEP-256"}, nil),
+			},
+			wantErrIs: ErrDecryption,
+			wantErr:   "parsing jwe",
+		},
+		"encrypted but not signed": {
+			token: encrypt(t, `{"sub":"00u1"}`, jose.Recipient{
+				Algorithm: jose.RSA_OAEP_256,
+				Key:       &rsaKey.PublicKey,
+			}, jose.A256GCM),
+			opts:      []Option{WithDecryptionKey("", rsaKey)},
+			wantErrIs: ErrMalformed,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, err := issuer.verifier(tt.opts...).ParseAndVerify(context.Background(), tt.token)
+			if tt.wantErrIs != nil {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, tt.wantErrIs)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "00u1", token.Claims["sub"])
+			assert.Equal(t, tt.token, token.Raw)
+			assert.Equal(t, issuer.kid, token.Verification.KeyID)
+			assert.Equal(t, tt.wantEncrypted, token.Verification.Encrypted)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// BackChannelLogoutEvent is the member of the 'events' claim of a logout
+	// token that identifies it as one, as described in the OIDC Back-Channel
+	// Logout specification.
+	BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	cacheKeyPrefixLogout = "logout_jti:"
+)
+
+// ErrLogoutTokenReplayed is returned when a logout token with the same 'jti'
+// claim has already been verified.
+var ErrLogoutTokenReplayed = errors.New("logout token has already been used")
+
+// LogoutToken is a verified logout token, which identifies the session or
+// the end-user to log out.
+type LogoutToken struct {
+	// Subject is the value of the 'sub' claim, which identifies the
+	// end-user. It may be empty if SessionID is not.
+	Subject string
+	// SessionID is the value of the 'sid' claim, which identifies the
+	// session. It may be empty if Subject is not.
+	SessionID string
+	// ID is the value of the 'jti' claim.
+	ID string
+	// JWT is the verified logout token.
+	JWT JWT
+}
+
+// ParseAndVerifyLogoutToken will parse the logout token of an OIDC
+// back-channel logout request and verify it as described in section 2.6 of
+// the OIDC Back-Channel Logout specification, in addition to verifying it
+// using the provided rules. It verifies that:
+//
+//   - The 'iss' claim equals the issuer of the Verifier.
+//   - The 'aud' claim contains the given client ID.
+//   - The token was not issued in the future and is not expired, allowing for
+//     the leeway set with WithLeeway. The 'exp' claim is required, so that a
+//     token cannot be used again once its 'jti' claim is no longer cached.
+//   - The 'jti' claim is present and has not been used before, according to
+//     the Cache of the Verifier, which must implement AddCache.
+//   - The 'events' claim contains the BackChannelLogoutEvent member.
+//   - Either the 'sub' or the 'sid' claim is present.
+//   - The 'nonce' claim is not present, which prevents ID tokens from being
+//     used as logout tokens.
+//
+// Once the token is verified, its 'jti' claim is recorded as used, so that it
+// cannot be used again.
+func (j Verifier) ParseAndVerifyLogoutToken(
+	ctx context.Context,
+	token string,
+	clientID string,
+	rules ...ClaimRule,
+) (LogoutToken, error) {
+	logoutToken, err := j.verifyLogoutToken(ctx, token, clientID, rules)
+	if err != nil {
+		return LogoutToken{}, err
+	}
+
+	if err = j.markLogoutTokenUsed(ctx, logoutToken); err != nil {
+		return LogoutToken{}, err
+	}
+
+	return logoutToken, nil
+}
+
+// verifyLogoutToken verifies the logout token like ParseAndVerifyLogoutToken,
+// but does not record its 'jti' claim as used.
+func (j Verifier) verifyLogoutToken(
+	ctx context.Context,
+	token string,
+	clientID string,
+	rules []ClaimRule,
+) (LogoutToken, error) {
+	logoutRules := []ClaimRule{
+		j.WithIssuerRule(),
+		WithAudienceRule(clientID),
+		j.WithIssuedAtRule(j.leeway),
+		j.WithExpirationRule(j.leeway),
+		WithRequiredClaimRule("jti"),
+		withLogoutEventRule(),
+		AnyOf(WithRequiredClaimRule("sub"), WithRequiredClaimRule("sid")),
+		WithForbiddenClaimRule("nonce"),
+	}
+
+	parsed, err := j.ParseAndVerify(ctx, token, append(logoutRules, rules...)...)
+	if err != nil {
+		return LogoutToken{}, err
+	}
+
+	logoutToken := LogoutToken{JWT: parsed}
+	logoutToken.Subject, _ = parsed.Claims["sub"].(string)
+	logoutToken.SessionID, _ = parsed.Claims["sid"].(string)
+	logoutToken.ID = fmt.Sprint(parsed.Claims["jti"])
+
+	if _, ok := j.cache.(AddCache); !ok {
+		return LogoutToken{}, ErrReplayProtectionUnsupported
+	}
+	if _, ok := j.cache.Get(ctx, cacheKeyPrefixLogout+logoutToken.ID); ok {
+		return LogoutToken{}, ErrLogoutTokenReplayed
+	}
+
+	return logoutToken, nil
+}
+
+// markLogoutTokenUsed atomically records the 'jti' claim of the logout token
+// as used, and fails with ErrLogoutTokenReplayed if it was already used.
+func (j Verifier) markLogoutTokenUsed(ctx context.Context, token LogoutToken) error {
+	added, err := j.markUsed(ctx, cacheKeyPrefixLogout+token.ID)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrLogoutTokenReplayed
+	}
+
+	return nil
+}
+
+// withLogoutEventRule verifies that the 'events' claim is an object with the
+// BackChannelLogoutEvent member, whose value is also an object.
+func withLogoutEventRule() ClaimRule {
+	return ClaimRule{
+		Key:  "events",
+		name: "logout_event",
+		Rule: func(value any) error {
+			events, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("expected an object but got a %T", value)
+			}
+
+			event, ok := events[BackChannelLogoutEvent]
+			if !ok {
+				return fmt.Errorf("missing member '%s'", BackChannelLogoutEvent)
+			}
+
+			if _, ok = event.(map[string]any); !ok {
+				return fmt.Errorf("expected member '%s' to be an object but got a %T", BackChannelLogoutEvent, event)
+			}
+
+			return nil
+		},
+	}
+}
+
+// BackChannelLogoutHandler returns an http.Handler which receives OIDC
+// back-channel logout requests, as described in section 2.5 of the OIDC
+// Back-Channel Logout specification. It verifies the logout token of each
+// request with [Verifier.ParseAndVerifyLogoutToken] and then calls logout,
+// which should terminate the sessions that the token identifies.
+//
+// It responds with 200 if logout succeeds, and otherwise with 400 and an
+// invalid_request error, as the specification requires. The 'jti' claim of
+// the logout token is only recorded as used once logout succeeds, so that the
+// OpenID Provider can retry the logout token if logout fails. Requests that do
+// not use the POST method are rejected with 405.
+func (j Verifier) BackChannelLogoutHandler(
+	clientID string,
+	logout func(ctx context.Context, token LogoutToken) error,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		rawToken := r.PostFormValue("logout_token")
+		if rawToken == "" {
+			writeLogoutError(w, "The request does not contain a logout token")
+			return
+		}
+
+		token, err := j.verifyLogoutToken(r.Context(), rawToken, clientID, nil)
+		if err != nil {
+			writeLogoutError(w, "The logout token is invalid")
+			return
+		}
+
+		if err = logout(r.Context(), token); err != nil {
+			writeLogoutError(w, "The logout failed")
+			return
+		}
+
+		// A concurrent request with the same logout token may have already
+		// recorded it as used, but the sessions are terminated either way.
+		_ = j.markLogoutTokenUsed(r.Context(), token)
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// writeLogoutError responds to a back-channel logout request with an
+// invalid_request error, as described in section 2.8 of the OIDC
+// Back-Channel Logout specification.
+func writeLogoutError(w http.ResponseWriter, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             "invalid_request",
+		"error_description": description,
+	})
+}
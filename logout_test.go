@@ -0,0 +1,263 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_ParseAndVerifyLogoutToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    issuer.URL,
+			"aud":    "0oa1",
+			"iat":    time.Now().Unix(),
+			"exp":    time.Now().Add(2 * time.Minute).Unix(),
+			"jti":    "bWJq",
+			"sub":    "00u1",
+			"sid":    "08a5019c-17e1-4977-8f42-65a12843ea02",
+			"events": map[string]any{BackChannelLogoutEvent: map[string]any{}},
+		}
+	}
+
+	cases := map[string]struct {
+		modify        func(jwt.MapClaims)
+		wantSubject   string
+		wantSessionID string
+		wantErr       string
+	}{
+		"valid": {
+			wantSubject:   "00u1",
+			wantSessionID: "08a5019c-17e1-4977-8f42-65a12843ea02",
+		},
+		"valid/only sid": {
+			modify:        func(c jwt.MapClaims) { delete(c, "sub") },
+			wantSessionID: "08a5019c-17e1-4977-8f42-65a12843ea02",
+		},
+		"valid/only sub": {
+			modify:      func(c jwt.MapClaims) { delete(c, "sid") },
+			wantSubject: "00u1",
+		},
+		"neither sub nor sid": {
+			modify: func(c jwt.MapClaims) {
+				delete(c, "sub")
+				delete(c, "sid")
+			},
+			wantErr: "claim 'sub' not found",
+		},
+		"wrong audience": {
+			modify:  func(c jwt.MapClaims) { c["aud"] = "0oa2" },
+			wantErr: "claim 'aud' is invalid",
+		},
+		"expired": {
+			modify:  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			wantErr: "claim 'exp' is invalid",
+		},
+		"missing exp": {
+			modify:  func(c jwt.MapClaims) { delete(c, "exp") },
+			wantErr: "claim 'exp' not found",
+		},
+		"missing jti": {
+			modify:  func(c jwt.MapClaims) { delete(c, "jti") },
+			wantErr: "claim 'jti' not found",
+		},
+		"missing event": {
+			modify: func(c jwt.MapClaims) {
+				c["events"] = map[string]any{"https://schemas.openid.net/secevent/caep/event-type/session-revoked": map[string]any{}}
+			},
+			wantErr: "claim 'events' is invalid: missing member 'http://schemas.openid.net/event/backchannel-logout'",
+		},
+		"event is not an object": {
+			modify:  func(c jwt.MapClaims) { c["events"] = map[string]any{BackChannelLogoutEvent: true} },
+			wantErr: "expected member 'http://schemas.openid.net/event/backchannel-logout' to be an object but got a bool",
+		},
+		"nonce present": {
+			modify:  func(c jwt.MapClaims) { c["nonce"] = "n-0S6_WzA2Mj" },
+			wantErr: "claim 'nonce' must not be present",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+
+			v := issuer.verifier(WithCache(NewDefaultCache()))
+			signed := issuer.sign(t, claims)
+
+			token, err := v.ParseAndVerifyLogoutToken(context.Background(), signed, "0oa1")
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantSubject, token.Subject)
+			assert.Equal(t, tt.wantSessionID, token.SessionID)
+			assert.Equal(t, "bWJq", token.ID)
+
+			_, err = v.ParseAndVerifyLogoutToken(context.Background(), signed, "0oa1")
+			assert.ErrorIs(t, err, ErrLogoutTokenReplayed)
+		})
+	}
+}
+
+func TestVerifier_BackChannelLogoutHandler(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	logoutToken := func(t *testing.T) string {
+		return issuer.sign(t, jwt.MapClaims{
+			"iss":    issuer.URL,
+			"aud":    "0oa1",
+			"iat":    time.Now().Unix(),
+			"exp":    time.Now().Add(2 * time.Minute).Unix(),
+			"jti":    time.Now().String(),
+			"sid":    "08a5019c-17e1-4977-8f42-65a12843ea02",
+			"events": map[string]any{BackChannelLogoutEvent: map[string]any{}},
+		})
+	}
+
+	cases := map[string]struct {
+		method     string
+		form       url.Values
+		logoutErr  error
+		wantStatus int
+		wantBody   string
+		wantLogout bool
+	}{
+		"success": {
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {logoutToken(t)}},
+			wantStatus: http.StatusOK,
+			wantLogout: true,
+		},
+		"wrong method": {
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"missing logout token": {
+			method:     http.MethodPost,
+			form:       url.Values{},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid_request","error_description":"The request does not contain a logout token"}`,
+		},
+		"invalid logout token": {
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {issuer.sign(t, jwt.MapClaims{"iss": issuer.URL})}},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid_request","error_description":"The logout token is invalid"}`,
+		},
+		"logout failed": {
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {logoutToken(t)}},
+			logoutErr:  errors.New("session store unavailable"),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"invalid_request","error_description":"The logout failed"}`,
+			wantLogout: true,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gotSessionID string
+			handler := issuer.verifier(WithCache(NewDefaultCache())).BackChannelLogoutHandler("0oa1", func(_ context.Context, token LogoutToken) error {
+				gotSessionID = token.SessionID
+				return tt.logoutErr
+			})
+
+			req := httptest.NewRequest(tt.method, "/logout", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+			if tt.wantLogout {
+				assert.Equal(t, "08a5019c-17e1-4977-8f42-65a12843ea02", gotSessionID)
+			} else {
+				assert.Empty(t, gotSessionID)
+			}
+		})
+	}
+}
+
+func TestVerifier_ParseAndVerifyLogoutToken_cacheWithoutAdd(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	_, err := issuer.verifier().ParseAndVerifyLogoutToken(context.Background(), issuer.sign(t, jwt.MapClaims{
+		"iss":    issuer.URL,
+		"aud":    "0oa1",
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(2 * time.Minute).Unix(),
+		"jti":    "bWJq",
+		"sid":    "08a5019c-17e1-4977-8f42-65a12843ea02",
+		"events": map[string]any{BackChannelLogoutEvent: map[string]any{}},
+	}), "0oa1")
+	assert.ErrorIs(t, err, ErrReplayProtectionUnsupported)
+}
+
+func TestVerifier_BackChannelLogoutHandler_retry(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	logoutToken := issuer.sign(t, jwt.MapClaims{
+		"iss":    issuer.URL,
+		"aud":    "0oa1",
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(2 * time.Minute).Unix(),
+		"jti":    "bWJq",
+		"sid":    "08a5019c-17e1-4977-8f42-65a12843ea02",
+		"events": map[string]any{BackChannelLogoutEvent: map[string]any{}},
+	})
+
+	attempts := 0
+	handler := issuer.verifier(WithCache(NewDefaultCache())).BackChannelLogoutHandler(
+		"0oa1",
+		func(context.Context, LogoutToken) error {
+			attempts++
+			if attempts == 1 {
+				return errors.New("session store unavailable")
+			}
+			return nil
+		},
+	)
+
+	post := func() *httptest.ResponseRecorder {
+		form := url.Values{"logout_token": {logoutToken}}
+		req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post()
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"invalid_request","error_description":"The logout failed"}`, rec.Body.String())
+
+	rec = post()
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = post()
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"invalid_request","error_description":"The logout token is invalid"}`, rec.Body.String())
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,405 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrNoToken is returned when a request does not contain a token.
+	ErrNoToken = errors.New("no token found in request")
+	// ErrInvalidRequest is returned when a request contains a token in a way
+	// that is not valid, such as an Authorization header with the Bearer
+	// scheme but no token. It corresponds to the invalid_request error code
+	// described in RFC 6750.
+	ErrInvalidRequest = errors.New("invalid_request")
+)
+
+type contextKeyJWT struct{}
+
+// ContextWithJWT returns a copy of ctx that contains the given JWT, which can
+// be retrieved with JWTFromContext.
+func ContextWithJWT(ctx context.Context, token JWT) context.Context {
+	return context.WithValue(ctx, contextKeyJWT{}, token)
+}
+
+// JWTFromContext returns the JWT stored in ctx by ContextWithJWT, such as the
+// JWT verified by the middleware returned by [Verifier.Middleware], and
+// whether there was one.
+func JWTFromContext(ctx context.Context) (JWT, bool) {
+	token, ok := ctx.Value(contextKeyJWT{}).(JWT)
+	return token, ok
+}
+
+// MiddlewareOption is used to configure the middleware returned by
+// [Verifier.Middleware].
+type MiddlewareOption func(*middleware)
+
+// WithRealm sets the realm that is included in the WWW-Authenticate header
+// when a request is rejected.
+func WithRealm(realm string) MiddlewareOption {
+	return func(m *middleware) {
+		m.realm = realm
+	}
+}
+
+// WithTokenExtractor sets the TokenExtractor used to extract the token from
+// each request. Defaults to the extractor returned by NewBearerExtractor, or
+// to one that extracts the token from the Authorization header with the DPoP
+// scheme if WithDPoP is used.
+func WithTokenExtractor(extractor TokenExtractor) MiddlewareOption {
+	return func(m *middleware) {
+		m.extractor = extractor
+	}
+}
+
+// WithPrincipalOptions sets the options used to create the Principal that is
+// stored in the context of each request, which can be retrieved with
+// PrincipalFromContext.
+func WithPrincipalOptions(opts ...PrincipalOption) MiddlewareOption {
+	return func(m *middleware) {
+		m.principalOpts = opts
+	}
+}
+
+// WithDPoP requires requests to present DPoP-bound access tokens with the
+// DPoP authorization scheme, along with a DPoP proof that is verified with
+// [Verifier.VerifyDPoPProof], as described in RFC 9449. Requests with an
+// invalid proof are rejected with the invalid_dpop_proof error code.
+func WithDPoP(opts ...DPoPOption) MiddlewareOption {
+	return func(m *middleware) {
+		m.dpop = newDPoPConfig(opts)
+	}
+}
+
+// WithCertificateBinding requires the token of each request to be bound to
+// the client certificate of the TLS connection that the request was received
+// on, which is verified with the rule created by WithCertificateBindingRule.
+// This requires the server to request client certificates, such as with
+// tls.RequireAndVerifyClientCert.
+func WithCertificateBinding() MiddlewareOption {
+	return func(m *middleware) {
+		m.certificateBinding = true
+	}
+}
+
+// WithAccessTokenProfile requires the token of each request to be a JWT
+// access token for the given audience as described in RFC 9068, which is
+// verified with [Verifier.ParseAndVerifyAccessToken]. This rejects other kinds
+// of tokens issued by the same issuer, such as ID tokens.
+func WithAccessTokenProfile(audience string) MiddlewareOption {
+	return func(m *middleware) {
+		m.accessTokenProfile = true
+		m.audience = audience
+	}
+}
+
+type middleware struct {
+	verifier           Verifier
+	rules              []ClaimRule
+	realm              string
+	extractor          TokenExtractor
+	defaultDeny        bool
+	principalOpts      []PrincipalOption
+	dpop               *dpopConfig
+	certificateBinding bool
+	accessTokenProfile bool
+	audience           string
+}
+
+// Middleware returns net/http middleware which extracts the bearer token from
+// the Authorization header of each request, or using the extractor set with
+// WithTokenExtractor, parses and verifies it with
+// ParseAndVerify using the given rules, and stores the verified JWT in the
+// context of the request so that it can be retrieved with JWTFromContext,
+// along with its Principal, which can be retrieved with PrincipalFromContext.
+//
+// If the request does not have a valid token, the middleware responds with a
+// WWW-Authenticate header as described in RFC 6750, or RFC 9449 if WithDPoP
+// is used, and a status code of:
+//
+//   - 401 if there is no token, if the token is invalid or if its DPoP proof
+//     is invalid.
+//   - 403 if the token does not have the required scopes, with an
+//     insufficient_scope error, or if it has a scope that WithNoScopesRule
+//     forbids, without an error code.
+//   - 400 if the request is malformed, such as if it contains more than one
+//     token.
+//   - 503 if the keys used to verify the token could not be fetched.
+func (j Verifier) Middleware(rules []ClaimRule, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	m := newMiddleware(j, rules, opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r, ok := m.authenticate(w, r, m.rules, nil); ok {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func newMiddleware(j Verifier, rules []ClaimRule, opts []MiddlewareOption) *middleware {
+	m := &middleware{
+		verifier:    j,
+		rules:       rules,
+		defaultDeny: true,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.extractor == nil {
+		m.extractor = NewBearerExtractor()
+		if m.dpop != nil {
+			m.extractor = NewHeaderExtractor("Authorization", "DPoP")
+		}
+	}
+
+	return m
+}
+
+// authenticate extracts the token from the request and verifies it using the
+// given rules. If the token is valid, it returns a copy of the request whose
+// context contains the verified JWT and its Principal. Otherwise it responds
+// to the request with an error and returns false. If requiredScopes is not
+// empty, it is used as the scope attribute of insufficient_scope errors.
+func (m *middleware) authenticate(
+	w http.ResponseWriter,
+	r *http.Request,
+	rules []ClaimRule,
+	requiredScopes []string,
+) (*http.Request, bool) {
+	token, err := m.extractor.ExtractToken(r)
+	if err != nil {
+		m.writeError(w, err, requiredScopes)
+		return nil, false
+	}
+
+	if m.certificateBinding {
+		rules = append(rules[:len(rules):len(rules)], WithCertificateBindingRule(PeerCertificate(r)))
+	}
+
+	if m.accessTokenProfile {
+		rules = append(m.verifier.accessTokenProfileRules(m.audience), rules...)
+	}
+
+	parsed, err := m.verifier.ParseAndVerify(r.Context(), token, rules...)
+	if err != nil {
+		m.writeError(w, err, requiredScopes)
+		return nil, false
+	}
+
+	if m.dpop != nil {
+		if err = m.verifier.verifyDPoPProof(r.Context(), r, parsed, m.dpop); err != nil {
+			m.writeError(w, err, requiredScopes)
+			return nil, false
+		}
+	}
+
+	ctx := ContextWithJWT(r.Context(), parsed)
+	ctx = ContextWithPrincipal(ctx, NewPrincipal(parsed, m.principalOpts...))
+
+	return r.WithContext(ctx), true
+}
+
+// writeError responds to the request with an error that is appropriate for
+// err.
+func (m *middleware) writeError(w http.ResponseWriter, err error, requiredScopes []string) {
+	if errors.Is(err, ErrKeyFetch) {
+		http.Error(w, "The token could not be verified", http.StatusServiceUnavailable)
+		return
+	}
+
+	berr := newBearerError(err, requiredScopes)
+	w.Header().Set("WWW-Authenticate", m.challenge(berr))
+
+	description := berr.description
+	if description == "" {
+		description = http.StatusText(berr.status)
+	}
+	http.Error(w, description, berr.status)
+}
+
+// bearerError is the error response to a request that was not authenticated,
+// as described by section 3 of RFC 6750.
+type bearerError struct {
+	status      int
+	code        string
+	description string
+	scope       string
+}
+
+// newBearerError returns the error response that is appropriate for err.
+func newBearerError(err error, requiredScopes []string) bearerError {
+	switch {
+	case errors.Is(err, ErrNoToken):
+		return bearerError{status: http.StatusUnauthorized}
+	case errors.Is(err, ErrInvalidRequest):
+		return bearerError{
+			status:      http.StatusBadRequest,
+			code:        "invalid_request",
+			description: "The request is malformed",
+		}
+	case errors.Is(err, ErrInvalidDPoPProof):
+		return bearerError{
+			status:      http.StatusUnauthorized,
+			code:        "invalid_dpop_proof",
+			description: "The DPoP proof is invalid",
+		}
+	case isAuthorizationFailure(err):
+		return authorizationError(err, requiredScopes)
+	default:
+		return bearerError{
+			status:      http.StatusUnauthorized,
+			code:        "invalid_token",
+			description: invalidTokenDescription(err),
+		}
+	}
+}
+
+// challenge returns the WWW-Authenticate challenge for the error response.
+func (m *middleware) challenge(berr bearerError) string {
+	params := make([]string, 0, 5)
+	if m.realm != "" {
+		params = append(params, authParam("realm", m.realm))
+	}
+	if berr.code != "" {
+		params = append(params, authParam("error", berr.code))
+	}
+	if berr.description != "" {
+		params = append(params, authParam("error_description", berr.description))
+	}
+	if berr.scope != "" {
+		params = append(params, authParam("scope", berr.scope))
+	}
+
+	challenge := "Bearer"
+	if m.dpop != nil {
+		challenge = "DPoP"
+		params = append(params, authParam("algs", strings.Join(dpopAlgorithms, " ")))
+	}
+
+	if len(params) != 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+
+	return challenge
+}
+
+// insufficientScope returns the scope attribute of an insufficient_scope
+// error, which is the scopes required by the middleware, or otherwise the
+// scopes that the token is missing.
+func insufficientScope(err error, requiredScopes []string) string {
+	if len(requiredScopes) != 0 {
+		return strings.Join(requiredScopes, " ")
+	}
+
+	missing := make([]string, 0)
+	for _, failure := range failureErrors(err) {
+		var scopeErr *InsufficientScopeError
+		if errors.As(failure, &scopeErr) {
+			missing = append(missing, scopeErr.Missing...)
+		}
+	}
+
+	return strings.Join(missing, " ")
+}
+
+// authorizationErrors are the errors of the rules that a valid token fails
+// when it does not grant access to the resource.
+var authorizationErrors = []error{ErrInsufficientScope, ErrForbiddenScope, ErrNotInGroup}
+
+// isAuthorizationFailure reports whether err only describes rules that a
+// valid token failed because it does not grant access to the resource, such
+// as missing scopes. If the token also failed any other rule then it is
+// invalid, which takes precedence as required by RFC 6750.
+func isAuthorizationFailure(err error) bool {
+	failures := failureErrors(err)
+	for _, failure := range failures {
+		if !isAuthorizationError(failure) {
+			return false
+		}
+	}
+
+	return len(failures) != 0
+}
+
+// failureErrors returns the errors of the rules that failed if err is a
+// *ValidationError, or err itself otherwise.
+func failureErrors(err error) []error {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		return []error{err}
+	}
+
+	errs := make([]error, 0, len(verr.Failures))
+	for i := range verr.Failures {
+		errs = append(errs, &verr.Failures[i])
+	}
+
+	return errs
+}
+
+func isAuthorizationError(err error) bool {
+	return slices.ContainsFunc(authorizationErrors, func(target error) bool {
+		return errors.Is(err, target)
+	})
+}
+
+// authorizationError returns the error response for a valid token that does
+// not grant access to the resource. Only missing scopes have an error code,
+// insufficient_scope, since RFC 6750 does not define one for the others.
+func authorizationError(err error, requiredScopes []string) bearerError {
+	if errors.Is(err, ErrInsufficientScope) {
+		return bearerError{
+			status:      http.StatusForbidden,
+			code:        "insufficient_scope",
+			description: "The token does not have the required scopes",
+			scope:       insufficientScope(err, requiredScopes),
+		}
+	}
+
+	description := "The token is not in any of the required groups"
+	if errors.Is(err, ErrForbiddenScope) {
+		description = "The token has scopes that are not allowed"
+	}
+
+	return bearerError{
+		status:      http.StatusForbidden,
+		description: description,
+	}
+}
+
+// invalidTokenDescription returns the error_description for an invalid_token
+// error. It deliberately does not include the details of err, which could
+// reveal how tokens are verified.
+func invalidTokenDescription(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "The token is expired"
+	case errors.Is(err, ErrMalformed):
+		return "The token is malformed"
+	case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrUnknownKID):
+		return "The token signature is invalid"
+	default:
+		return "The token is invalid"
+	}
+}
+
+// authParam formats an auth-param for the WWW-Authenticate header as a quoted
+// string, dropping any characters that RFC 6750 does not allow.
+func authParam(name, value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return -1
+		}
+		return r
+	}, value)
+
+	return fmt.Sprintf("%s=%q", name, value)
+}
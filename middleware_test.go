@@ -0,0 +1,145 @@
+package verifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Middleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	validToken := issuer.sign(t, jwt.MapClaims{
+		"sub": "00u1",
+		"exp": float64(time.Now().Add(time.Hour).Unix()),
+		"scp": []any{"read"},
+	})
+	expiredToken := issuer.sign(t, jwt.MapClaims{
+		"sub": "00u1",
+		"exp": float64(time.Now().Add(-time.Hour).Unix()),
+		"scp": []any{"read"},
+	})
+
+	cases := map[string]struct {
+		authorization       string
+		rules               []ClaimRule
+		keysUnavailable     bool
+		wantStatus          int
+		wantWWWAuthenticate string
+		wantSubject         string
+	}{
+		"no authorization header": {
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api"`,
+		},
+		"other scheme": {
+			authorization:       "Basic Zm9vOmJhcg==",
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api"`,
+		},
+		"no token": {
+			authorization:       "Bearer ",
+			wantStatus:          http.StatusBadRequest,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_request", error_description="The request is malformed"`,
+		},
+		"malformed token": {
+			authorization:       "Bearer deadbeef",
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_token", error_description="The token is malformed"`,
+		},
+		"expired token": {
+			authorization:       "Bearer " + expiredToken,
+			rules:               []ClaimRule{WithExpirationRule(0)},
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_token", error_description="The token is expired"`,
+		},
+		"invalid claim": {
+			authorization:       "Bearer " + validToken,
+			rules:               []ClaimRule{WithCustomClaimExactMatchRule("sub", "00u2")},
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_token", error_description="The token is invalid"`,
+		},
+		"insufficient scope": {
+			authorization:       "Bearer " + validToken,
+			rules:               []ClaimRule{WithAllScopesRule("read", "write", "admin")},
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer realm="api", error="insufficient_scope", error_description="The token does not have the required scopes", scope="write admin"`,
+		},
+		"forbidden scope": {
+			authorization:       "Bearer " + validToken,
+			rules:               []ClaimRule{WithNoScopesRule("read")},
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer realm="api", error_description="The token has scopes that are not allowed"`,
+		},
+		"forbidden and insufficient scope": {
+			authorization:       "Bearer " + validToken,
+			rules:               []ClaimRule{WithNoScopesRule("read"), WithAllScopesRule("admin")},
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer realm="api", error="insufficient_scope", error_description="The token does not have the required scopes", scope="admin"`,
+		},
+		"expired token with insufficient scope": {
+			authorization:       "Bearer " + expiredToken,
+			rules:               []ClaimRule{WithExpirationRule(0), WithAllScopesRule("write")},
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_token", error_description="The token is expired"`,
+		},
+		"invalid claim with insufficient scope": {
+			authorization:       "Bearer " + validToken,
+			rules:               []ClaimRule{WithCustomClaimExactMatchRule("sub", "00u2"), WithAllScopesRule("write")},
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer realm="api", error="invalid_token", error_description="The token is invalid"`,
+		},
+		"keys unavailable": {
+			authorization:   "Bearer " + validToken,
+			keysUnavailable: true,
+			wantStatus:      http.StatusServiceUnavailable,
+		},
+		"success": {
+			authorization: "bearer " + validToken,
+			rules: []ClaimRule{
+				WithExpirationRule(0),
+				WithAllScopesRule("read"),
+			},
+			wantStatus:  http.StatusOK,
+			wantSubject: "00u1",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := issuer.verifier()
+			if tt.keysUnavailable {
+				v = New("http://127.0.0.1:0", WithCache(NewNopCache()))
+			}
+
+			var gotSubject any
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token, ok := JWTFromContext(r.Context())
+				require.True(t, ok)
+				gotSubject = token.Claims["sub"]
+				w.WriteHeader(http.StatusOK)
+			})
+
+			handler := v.Middleware(tt.rules, WithRealm("api"))(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantWWWAuthenticate, rec.Header().Get("WWW-Authenticate"))
+			if tt.wantSubject != "" {
+				assert.Equal(t, tt.wantSubject, gotSubject)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package verifier
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCertificateBinding is returned by the rule created with
+// WithCertificateBindingRule when the token is not bound to the client
+// certificate, including when there is no client certificate.
+var ErrCertificateBinding = errors.New("token is not bound to the client certificate")
+
+// WithCertificateBindingRule will verify that the token is bound to the given
+// client certificate, as described in section 3 of RFC 8705, which means that
+// the 'cnf.x5t#S256' claim must be the base64url-encoded SHA-256 thumbprint of
+// the certificate. The certificate is usually the first of the peer
+// certificates of the TLS connection that the token was received on, which
+// PeerCertificate returns.
+//
+// If the certificate is nil, such as when the client did not present one, the
+// rule fails.
+func WithCertificateBindingRule(cert *x509.Certificate) ClaimRule {
+	return ClaimRule{
+		Key:  "cnf.x5t#S256",
+		name: "certificate_binding",
+		token: func(_ context.Context, token JWT) error {
+			value, err := lookupClaim(token.Claims, "cnf.x5t#S256")
+			if err != nil {
+				return &sentinelError{
+					sentinel: ErrCertificateBinding,
+					err:      errors.New("token is not bound to a client certificate"),
+				}
+			}
+
+			got, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("expected a %T but got a %T", got, value)
+			}
+
+			if cert == nil {
+				return &sentinelError{
+					sentinel: ErrCertificateBinding,
+					err:      errors.New("token is bound to a client certificate but none was presented"),
+				}
+			}
+
+			if got != CertificateThumbprint(cert) {
+				return &sentinelError{
+					sentinel: ErrCertificateBinding,
+					err:      errors.New("token is bound to a different client certificate"),
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// CertificateThumbprint returns the base64url-encoded SHA-256 thumbprint of
+// the certificate, which is the value of the 'cnf.x5t#S256' claim of tokens
+// that are bound to it.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PeerCertificate returns the client certificate of the TLS connection that
+// the request was received on, or nil if there is none.
+func PeerCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
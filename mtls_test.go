@@ -0,0 +1,147 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCertificate(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "orders-service"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestWithCertificateBindingRule(t *testing.T) {
+	cert := newTestCertificate(t)
+	other := newTestCertificate(t)
+
+	cases := map[string]struct {
+		claims    map[string]any
+		cert      *x509.Certificate
+		wantErr   string
+		wantErrIs error
+	}{
+		"bound": {
+			claims: map[string]any{"cnf": map[string]any{"x5t#S256": CertificateThumbprint(cert)}},
+			cert:   cert,
+		},
+		"bound to different certificate": {
+			claims:    map[string]any{"cnf": map[string]any{"x5t#S256": CertificateThumbprint(other)}},
+			cert:      cert,
+			wantErr:   "claim 'cnf.x5t#S256' is invalid: token is bound to a different client certificate",
+			wantErrIs: ErrCertificateBinding,
+		},
+		"no certificate": {
+			claims:    map[string]any{"cnf": map[string]any{"x5t#S256": CertificateThumbprint(cert)}},
+			wantErr:   "claim 'cnf.x5t#S256' is invalid: token is bound to a client certificate but none was presented",
+			wantErrIs: ErrCertificateBinding,
+		},
+		"not bound": {
+			claims:    map[string]any{"sub": "0oa1"},
+			cert:      cert,
+			wantErr:   "claim 'cnf.x5t#S256' is invalid: token is not bound to a client certificate",
+			wantErrIs: ErrCertificateBinding,
+		},
+		"invalid thumbprint": {
+			claims:  map[string]any{"cnf": map[string]any{"x5t#S256": 1234}},
+			cert:    cert,
+			wantErr: "claim 'cnf.x5t#S256' is invalid: expected a string but got a int",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := WithCertificateBindingRule(tt.cert).verify(context.Background(), JWT{Claims: tt.claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMiddleware_certificateBinding(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cert := newTestCertificate(t)
+
+	boundToken := issuer.sign(t, jwt.MapClaims{"cnf": map[string]any{"x5t#S256": CertificateThumbprint(cert)}})
+
+	cases := map[string]struct {
+		token      string
+		peerCerts  []*x509.Certificate
+		wantStatus int
+	}{
+		"bound": {
+			token:      boundToken,
+			peerCerts:  []*x509.Certificate{cert},
+			wantStatus: http.StatusOK,
+		},
+		"no client certificate": {
+			token:      boundToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		"different client certificate": {
+			token:      boundToken,
+			peerCerts:  []*x509.Certificate{newTestCertificate(t)},
+			wantStatus: http.StatusUnauthorized,
+		},
+		"unbound token": {
+			token:      issuer.sign(t, jwt.MapClaims{"sub": "0oa1"}),
+			peerCerts:  []*x509.Certificate{cert},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := issuer.verifier().Middleware(nil, WithCertificateBinding())(next)
+
+			req := httptest.NewRequest(http.MethodGet, "https://orders.internal/orders", nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: tt.peerCerts}
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
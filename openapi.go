@@ -0,0 +1,264 @@
+package verifier
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the HTTP methods that an OpenAPI path item can describe
+// operations for.
+var openAPIMethods = []string{
+	http.MethodGet,
+	http.MethodPut,
+	http.MethodPost,
+	http.MethodDelete,
+	http.MethodOptions,
+	http.MethodHead,
+	http.MethodPatch,
+	http.MethodTrace,
+}
+
+// openAPIInvalidWildcardChars matches the characters in an OpenAPI path
+// parameter name that are not allowed in the name of an http.ServeMux
+// wildcard.
+var openAPIInvalidWildcardChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+type openAPIDocument struct {
+	Security *[]map[string][]string          `yaml:"security"`
+	Paths    map[string]map[string]yaml.Node `yaml:"paths"`
+}
+
+type openAPIOperation struct {
+	OperationID string                 `yaml:"operationId"`
+	Security    *[]map[string][]string `yaml:"security"`
+}
+
+// OpenAPIOption is used to configure LoadOpenAPIPolicies.
+type OpenAPIOption func(*openAPIConfig)
+
+// WithOpenAPIStrict causes LoadOpenAPIPolicies to return an error if any
+// operation does not declare a security requirement that uses the security
+// scheme, either itself or through the top-level security requirements of
+// the document. Without it, such operations are left out of the PolicyTable.
+func WithOpenAPIStrict() OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.strict = true
+	}
+}
+
+// WithOpenAPIBasePath sets a path that is prepended to every path in the
+// document, which is needed when the paths are relative to a server URL that
+// has a path, such as https://api.example.com/v1.
+func WithOpenAPIBasePath(basePath string) OpenAPIOption {
+	return func(c *openAPIConfig) {
+		c.basePath = basePath
+	}
+}
+
+type openAPIConfig struct {
+	strict   bool
+	basePath string
+}
+
+// LoadOpenAPIPolicies reads an OpenAPI 3 document in either JSON or YAML and
+// returns a PolicyTable with a RoutePolicy for each of its operations, which
+// can be enforced with [Verifier.PolicyMiddleware]. This allows the document
+// to be the single source of truth for the scopes that each operation
+// requires.
+//
+// The scopes of each operation are taken from its security requirements that
+// use the security scheme with the given name, such as
+// `security: [{okta: [orders:read]}]`, or from the top-level security
+// requirements of the document if the operation does not declare any. If an
+// operation has more than one such requirement, the token must satisfy at
+// least one of them. Operations with an empty list of security requirements
+// are public, so no token is required.
+//
+// Since the wildcards of http.ServeMux patterns must be whole path segments,
+// an error is returned for operations whose path has a segment that contains
+// a path parameter along with anything else, such as /files/{name}.json.
+func LoadOpenAPIPolicies(r io.Reader, scheme string, opts ...OpenAPIOption) (PolicyTable, error) {
+	var cfg openAPIConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var doc openAPIDocument
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding openapi document: %w", err)
+	}
+
+	l := openAPILoader{
+		doc:      doc,
+		scheme:   scheme,
+		basePath: cfg.basePath,
+		policies: make(PolicyTable),
+		missing:  make([]string, 0),
+	}
+
+	// Sort the paths so that errors are deterministic.
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := l.addPath(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.strict && len(l.missing) != 0 {
+		return nil, fmt.Errorf(
+			"operation(s) without security requirements using scheme '%s': %s",
+			scheme,
+			strings.Join(l.missing, ", "),
+		)
+	}
+
+	return l.policies, nil
+}
+
+// openAPILoader builds a PolicyTable from the operations of an OpenAPI
+// document.
+type openAPILoader struct {
+	doc      openAPIDocument
+	scheme   string
+	basePath string
+	policies PolicyTable
+	// missing describes the operations without security requirements that
+	// use the security scheme.
+	missing []string
+}
+
+// addPath adds the policies of the operations of the given path.
+func (l *openAPILoader) addPath(p string) error {
+	item := l.doc.Paths[p]
+	for _, method := range openAPIMethods {
+		node, ok := item[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+
+		if err := l.addOperation(method, p, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addOperation adds the policy of the operation with the given method and
+// path, or records it as missing if it has no security requirements that use
+// the security scheme.
+func (l *openAPILoader) addOperation(method, p string, node yaml.Node) error {
+	var op openAPIOperation
+	if err := node.Decode(&op); err != nil {
+		return fmt.Errorf("decoding operation %s %s: %w", method, p, err)
+	}
+
+	security := op.Security
+	if security == nil {
+		security = l.doc.Security
+	}
+
+	operation := fmt.Sprintf("%s %s", method, p)
+	if op.OperationID != "" {
+		operation += fmt.Sprintf(" (%s)", op.OperationID)
+	}
+
+	policy, ok := openAPIPolicy(security, l.scheme)
+	if !ok {
+		l.missing = append(l.missing, operation)
+		return nil
+	}
+
+	pattern, err := openAPIPattern(l.basePath, p)
+	if err != nil {
+		return fmt.Errorf("operation %s: %w", operation, err)
+	}
+
+	l.policies[fmt.Sprintf("%s %s", method, pattern)] = policy
+
+	return nil
+}
+
+// openAPIPolicy returns the RoutePolicy for the given security requirements,
+// and false if none of them use the security scheme.
+func openAPIPolicy(security *[]map[string][]string, scheme string) (RoutePolicy, bool) {
+	if security == nil {
+		return RoutePolicy{}, false
+	}
+
+	if len(*security) == 0 {
+		return RoutePolicy{Public: true}, true
+	}
+
+	alternatives := make([][]string, 0, len(*security))
+	for _, requirement := range *security {
+		if scopes, ok := requirement[scheme]; ok {
+			alternatives = append(alternatives, scopes)
+		}
+	}
+
+	switch len(alternatives) {
+	case 0:
+		return RoutePolicy{}, false
+	case 1:
+		return RoutePolicy{Scopes: alternatives[0]}, true
+	}
+
+	rules := make([]ClaimRule, 0, len(alternatives))
+	for _, scopes := range alternatives {
+		rules = append(rules, WithAllScopesRule(scopes...))
+	}
+
+	return RoutePolicy{Rules: []ClaimRule{AnyOf(rules...)}}, true
+}
+
+// openAPIPattern converts an OpenAPI path template into an http.ServeMux
+// pattern. It returns an error if a path parameter is not a whole segment,
+// which http.ServeMux wildcards must be.
+func openAPIPattern(basePath, p string) (string, error) {
+	segments := strings.Split(path.Join("/", basePath, p), "/")
+	for i, segment := range segments {
+		if !strings.ContainsAny(segment, "{}") {
+			continue
+		}
+
+		name, ok := strings.CutPrefix(segment, "{")
+		if ok {
+			name, ok = strings.CutSuffix(name, "}")
+		}
+		if !ok || strings.ContainsAny(name, "{}") {
+			return "", fmt.Errorf(
+				"path segment '%s' must be a single path parameter, since http.ServeMux wildcards must be whole "+
+					"path segments",
+				segment,
+			)
+		}
+
+		segments[i] = "{" + openAPIInvalidWildcardChars.ReplaceAllString(name, "_") + "}"
+	}
+
+	pattern := strings.Join(segments, "/")
+
+	// OpenAPI paths always match exactly, whereas http.ServeMux patterns that
+	// end in a slash match any path with that prefix.
+	if strings.HasSuffix(p, "/") && pattern != "/" {
+		pattern += "/"
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+
+	return pattern, nil
+}
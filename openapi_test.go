@@ -0,0 +1,217 @@
+package verifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOpenAPIPolicies(t *testing.T) {
+	const yamlDoc = `
+openapi: 3.0.3
+info:
+  title: Orders
+  version: 1.0.0
+security:
+  - okta: [orders:read]
+paths:
+  /orders:
+    get:
+      operationId: listOrders
+    post:
+      operationId: createOrder
+      security:
+        - okta: [orders:write]
+  /orders/{order-id}:
+    delete:
+      operationId: deleteOrder
+      security:
+        - okta: [orders:write, orders:delete]
+        - okta: [admin]
+  /health/:
+    get:
+      operationId: health
+      security: []
+  /legacy:
+    get:
+      operationId: legacy
+      security:
+        - apiKey: []
+`
+
+	const jsonDoc = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/orders": {
+      "get": {"operationId": "listOrders", "security": [{"okta": ["orders:read"]}]},
+      "post": {"operationId": "createOrder"}
+    }
+  }
+}`
+
+	cases := map[string]struct {
+		doc          string
+		opts         []OpenAPIOption
+		wantPatterns []string
+		wantScopes   map[string][]string
+		wantErr      string
+	}{
+		"yaml": {
+			doc: yamlDoc,
+			wantPatterns: []string{
+				"GET /orders",
+				"POST /orders",
+				"DELETE /orders/{order_id}",
+				"GET /health/{$}",
+			},
+			wantScopes: map[string][]string{
+				"GET /orders":  {"orders:read"},
+				"POST /orders": {"orders:write"},
+			},
+		},
+		"yaml with base path": {
+			doc:  yamlDoc,
+			opts: []OpenAPIOption{WithOpenAPIBasePath("/v1")},
+			wantPatterns: []string{
+				"GET /v1/orders",
+				"POST /v1/orders",
+				"DELETE /v1/orders/{order_id}",
+				"GET /v1/health/{$}",
+			},
+		},
+		"yaml/strict": {
+			doc:     yamlDoc,
+			opts:    []OpenAPIOption{WithOpenAPIStrict()},
+			wantErr: "operation(s) without security requirements using scheme 'okta': GET /legacy (legacy)",
+		},
+		"json": {
+			doc:          jsonDoc,
+			wantPatterns: []string{"GET /orders"},
+		},
+		"json/strict": {
+			doc:     jsonDoc,
+			opts:    []OpenAPIOption{WithOpenAPIStrict()},
+			wantErr: "operation(s) without security requirements using scheme 'okta': POST /orders (createOrder)",
+		},
+		"partial path parameter": {
+			doc: `
+paths:
+  /files/{name}.json:
+    get:
+      operationId: getFile
+      security: [{okta: [files:read]}]
+`,
+			wantErr: "operation GET /files/{name}.json (getFile): path segment '{name}.json' must be a single " +
+				"path parameter, since http.ServeMux wildcards must be whole path segments",
+		},
+		"multiple path parameters in segment": {
+			doc: `
+paths:
+  /reports/{id}-{fmt}:
+    get:
+      security: [{okta: [reports:read]}]
+`,
+			wantErr: "operation GET /reports/{id}-{fmt}: path segment '{id}-{fmt}' must be a single " +
+				"path parameter, since http.ServeMux wildcards must be whole path segments",
+		},
+		"invalid document": {
+			doc:     `paths: [`,
+			wantErr: "decoding openapi document: yaml: line 1: did not find expected node content",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			policies, err := LoadOpenAPIPolicies(strings.NewReader(tt.doc), "okta", tt.opts...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			gotPatterns := make([]string, 0, len(policies))
+			for pattern := range policies {
+				gotPatterns = append(gotPatterns, pattern)
+			}
+			assert.ElementsMatch(t, tt.wantPatterns, gotPatterns)
+
+			for pattern, scopes := range tt.wantScopes {
+				assert.Equal(t, scopes, policies[pattern].Scopes, pattern)
+			}
+		})
+	}
+}
+
+func TestLoadOpenAPIPolicies_enforced(t *testing.T) {
+	const doc = `
+paths:
+  /orders/{id}:
+    delete:
+      security:
+        - okta: [orders:write, orders:delete]
+        - okta: [admin]
+  /health:
+    get:
+      security: []
+`
+
+	policies, err := LoadOpenAPIPolicies(strings.NewReader(doc), "okta")
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	mw, err := issuer.verifier().PolicyMiddleware(nil, policies)
+	require.NoError(t, err)
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := map[string]struct {
+		method     string
+		path       string
+		scopes     []any
+		wantStatus int
+	}{
+		"public": {
+			method:     http.MethodGet,
+			path:       "/health",
+			wantStatus: http.StatusOK,
+		},
+		"first alternative": {
+			method:     http.MethodDelete,
+			path:       "/orders/1234",
+			scopes:     []any{"orders:write", "orders:delete"},
+			wantStatus: http.StatusOK,
+		},
+		"second alternative": {
+			method:     http.MethodDelete,
+			path:       "/orders/1234",
+			scopes:     []any{"admin"},
+			wantStatus: http.StatusOK,
+		},
+		"insufficient scope": {
+			method:     http.MethodDelete,
+			path:       "/orders/1234",
+			scopes:     []any{"orders:write"},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.scopes != nil {
+				req.Header.Set("Authorization", "Bearer "+issuer.sign(t, map[string]any{"scp": tt.scopes}))
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package verifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// claimNotFoundError is returned by lookupClaim when there is no value at the
+// given key.
+type claimNotFoundError struct {
+	key     string
+	segment string
+	reason  string
+}
+
+func (e *claimNotFoundError) Error() string {
+	if e.segment == e.key {
+		return fmt.Sprintf("claim '%s' not found", e.key)
+	}
+
+	msg := fmt.Sprintf("claim '%s' not found: path not found at segment '%s'", e.key, e.segment)
+	if e.reason != "" {
+		msg += ": " + e.reason
+	}
+
+	return msg
+}
+
+// Is reports whether target is ErrMissingClaim.
+func (e *claimNotFoundError) Is(target error) bool {
+	return target == ErrMissingClaim
+}
+
+// lookupClaim returns the value of the claim identified by key, which may be
+// any of the following:
+//
+//   - The name of a top-level claim, such as "tenant.id". This takes
+//     precedence over the other forms so that top-level claims whose names
+//     contain dots can still be addressed.
+//   - A path in dot notation, such as "tenant.id" or "groups.0", where each
+//     segment is the key of an object or the index of an array.
+//   - A JSON Pointer as described in RFC 6901, such as "/cnf/jkt" or
+//     "/groups/0".
+func lookupClaim(claims map[string]any, key string) (any, error) {
+	var segments []string
+	switch {
+	case strings.HasPrefix(key, "/"):
+		segments = pointerSegments(key)
+	default:
+		if v, ok := claims[key]; ok {
+			return v, nil
+		}
+		segments = strings.Split(key, ".")
+	}
+
+	var current any = claims
+	for _, segment := range segments {
+		notFound := &claimNotFoundError{key: key, segment: segment}
+		if len(segments) == 1 {
+			notFound.segment = key
+		}
+
+		next, err := lookupSegment(current, segment, notFound)
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+
+	return current, nil
+}
+
+// pointerSegments returns the unescaped reference tokens of a JSON Pointer.
+func pointerSegments(pointer string) []string {
+	segments := strings.Split(pointer[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+	}
+	return segments
+}
+
+// lookupSegment returns the value of the given segment of a path within
+// current, which is the key of an object or the index of an array. If it is
+// not found, notFound is returned with the reason.
+func lookupSegment(current any, segment string, notFound *claimNotFoundError) (any, error) {
+	switch v := current.(type) {
+	case map[string]any:
+		next, ok := v[segment]
+		if !ok {
+			return nil, notFound
+		}
+		return next, nil
+	case []any:
+		i, err := strconv.Atoi(segment)
+		if err != nil {
+			notFound.reason = "expected an array index"
+			return nil, notFound
+		}
+		if i < 0 || i >= len(v) {
+			notFound.reason = fmt.Sprintf("array index out of range with length %d", len(v))
+			return nil, notFound
+		}
+		return v[i], nil
+	default:
+		notFound.reason = fmt.Sprintf("expected an object or an array but got a %T", current)
+		return nil, notFound
+	}
+}
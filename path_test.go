@@ -0,0 +1,116 @@
+package verifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lookupClaim(t *testing.T) {
+	const rawClaims = `{
+		"sub": "user",
+		"https://example.com/roles": ["admin"],
+		"tenant.id": "top-level",
+		"tenant": {"id": "acme", "tier": 2},
+		"groups": ["Everyone", "Ops"],
+		"cnf": {"jkt": "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"},
+		"a/b": {"c~d": true}
+	}`
+
+	cases := map[string]struct {
+		key       string
+		useNumber bool
+		want      any
+		wantErr   string
+	}{
+		"top-level claim": {
+			key:  "sub",
+			want: "user",
+		},
+		"top-level claim with dots and slashes": {
+			key:  "https://example.com/roles",
+			want: []any{"admin"},
+		},
+		"top-level claim takes precedence": {
+			key:  "tenant.id",
+			want: "top-level",
+		},
+		"dot notation": {
+			key:  "cnf.jkt",
+			want: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I",
+		},
+		"dot notation with array index": {
+			key:  "groups.1",
+			want: "Ops",
+		},
+		"dot notation with number": {
+			key:  "tenant.tier",
+			want: float64(2),
+		},
+		"dot notation with json number": {
+			key:       "tenant.tier",
+			useNumber: true,
+			want:      json.Number("2"),
+		},
+		"json pointer": {
+			key:  "/tenant/id",
+			want: "acme",
+		},
+		"json pointer with array index": {
+			key:  "/groups/0",
+			want: "Everyone",
+		},
+		"json pointer with escaped characters": {
+			key:  "/a~1b/c~0d",
+			want: true,
+		},
+		"missing top-level claim": {
+			key:     "uid",
+			wantErr: "claim 'uid' not found",
+		},
+		"missing nested claim": {
+			key:     "tenant.region",
+			wantErr: "claim 'tenant.region' not found: path not found at segment 'region'",
+		},
+		"missing nested claim in json pointer": {
+			key:     "/cnf/x5t#S256",
+			wantErr: "claim '/cnf/x5t#S256' not found: path not found at segment 'x5t#S256'",
+		},
+		"invalid array index": {
+			key:     "groups.first",
+			wantErr: "claim 'groups.first' not found: path not found at segment 'first': expected an array index",
+		},
+		"array index out of range": {
+			key:     "groups.2",
+			wantErr: "claim 'groups.2' not found: path not found at segment '2': array index out of range with length 2",
+		},
+		"segment on non-object": {
+			key:     "sub.id",
+			wantErr: "claim 'sub.id' not found: path not found at segment 'id': expected an object or an array but got a string",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(rawClaims))
+			if tt.useNumber {
+				decoder.UseNumber()
+			}
+
+			var claims map[string]any
+			require.NoError(t, decoder.Decode(&claims))
+
+			got, err := lookupClaim(claims, tt.key)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
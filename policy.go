@@ -0,0 +1,156 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotInGroup is returned when a token is not in any of the groups required
+// by the Groups of a RoutePolicy. Like ErrInsufficientScope, it means that the
+// token is valid but does not grant access to the route, so the middleware
+// returned by [Verifier.PolicyMiddleware] responds with a 403 status code.
+var ErrNotInGroup = errors.New("token is not in any of the required groups")
+
+// RoutePolicy describes what a token must have in order to access a route.
+type RoutePolicy struct {
+	// Scopes are the scopes that the token must have, all of which are
+	// required.
+	Scopes []string
+	// Groups are the groups that the token must be in, according to the
+	// 'groups' claim. The token must be in at least one of them.
+	Groups []string
+	// Audiences are the audiences that the token must be intended for. The
+	// 'aud' claim must contain at least one of them.
+	Audiences []string
+	// Rules are any additional rules that the token must pass.
+	Rules []ClaimRule
+	// Public, if true, means that the route does not require a token, so
+	// requests are passed through without being verified.
+	Public bool
+}
+
+func (p RoutePolicy) rules() []ClaimRule {
+	rules := make([]ClaimRule, 0, len(p.Rules)+3)
+	if len(p.Audiences) != 0 {
+		rules = append(rules, WithAudiencesRule(p.Audiences...))
+	}
+	if len(p.Scopes) != 0 {
+		rules = append(rules, WithAllScopesRule(p.Scopes...))
+	}
+	if len(p.Groups) != 0 {
+		rules = append(rules, withGroupsRule(p.Groups))
+	}
+	return append(rules, p.Rules...)
+}
+
+// withGroupsRule will verify that the 'groups' claim contains at least one of
+// the given groups. Since Okta leaves out the claim if the token is not in
+// any of the groups that it is filtered to, a token without it is not in any
+// of the groups either.
+func withGroupsRule(groups []string) ClaimRule {
+	containsAny := WithCustomClaimContainsAnyRule("groups", groups).Rule
+	return ClaimRule{
+		Key:  "groups",
+		name: "groups",
+		token: func(_ context.Context, token JWT) error {
+			value, err := lookupClaim(token.Claims, "groups")
+			if err != nil {
+				value = []any{}
+			}
+
+			if err := containsAny(value); err != nil {
+				return &sentinelError{sentinel: ErrNotInGroup, err: err}
+			}
+
+			return nil
+		},
+	}
+}
+
+// PolicyTable maps routes to the policy that tokens must satisfy to access
+// them. Each route is a pattern as accepted by http.ServeMux, such as
+// "GET /orders/{id}", and requests are matched to routes in the same way as
+// an http.ServeMux would, so the patterns should be the same as the ones
+// that handlers are registered with.
+type PolicyTable map[string]RoutePolicy
+
+// WithDefaultDeny sets whether the middleware returned by
+// [Verifier.PolicyMiddleware] rejects requests that do not match any of the
+// routes in the PolicyTable. If false, such requests only need a token that
+// passes the rules given to PolicyMiddleware. Defaults to true.
+func WithDefaultDeny(deny bool) MiddlewareOption {
+	return func(m *middleware) {
+		m.defaultDeny = deny
+	}
+}
+
+// PolicyMiddleware returns net/http middleware which enforces the policy of
+// the route that each request matches. It behaves like the middleware
+// returned by [Verifier.Middleware], except that each request is verified
+// using both the given rules, which apply to all routes, and the rules of the
+// policy for its route.
+//
+// If the token does not have the scopes required by the policy, the request
+// is rejected with an insufficient_scope error listing all of the scopes
+// that the policy requires. If the token is not in any of the groups of the
+// policy, the request is rejected with a 403 status code. Requests that do not match any route are rejected
+// with a 403 status code unless WithDefaultDeny(false) is used.
+//
+// An error is returned if any of the routes are not valid http.ServeMux
+// patterns, or if any two of them conflict.
+func (j Verifier) PolicyMiddleware(
+	rules []ClaimRule,
+	policies PolicyTable,
+	opts ...MiddlewareOption,
+) (func(http.Handler) http.Handler, error) {
+	m := newMiddleware(j, rules, opts)
+
+	mux := http.NewServeMux()
+	routeRules := make(map[string][]ClaimRule, len(policies))
+	for pattern, policy := range policies {
+		if err := registerPattern(mux, pattern); err != nil {
+			return nil, err
+		}
+		routeRules[pattern] = append(append([]ClaimRule{}, rules...), policy.rules()...)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, pattern := mux.Handler(r)
+
+			if policies[pattern].Public {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rules, ok := routeRules[pattern]
+			if !ok {
+				if m.defaultDeny {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+				rules = m.rules
+			}
+
+			if r, ok := m.authenticate(w, r, rules, policies[pattern].Scopes); ok {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}, nil
+}
+
+// registerPattern registers the pattern with the mux, returning an error
+// instead of panicking if the pattern is invalid or conflicts with another.
+func registerPattern(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("registering route %q: %v", pattern, r)
+		}
+	}()
+
+	mux.Handle(pattern, http.NotFoundHandler())
+
+	return nil
+}
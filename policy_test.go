@@ -0,0 +1,166 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_PolicyMiddleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	token := issuer.sign(t, jwt.MapClaims{
+		"sub":    "00u1",
+		"aud":    "api://orders",
+		"exp":    float64(time.Now().Add(time.Hour).Unix()),
+		"scp":    []any{"orders:read"},
+		"groups": []any{"Everyone", "Support"},
+	})
+
+	policies := PolicyTable{
+		"GET /orders/{id}": {
+			Scopes:    []string{"orders:read"},
+			Audiences: []string{"api://orders"},
+		},
+		"DELETE /orders/{id}": {
+			Scopes: []string{"orders:read", "orders:write"},
+		},
+		"PUT /orders/{id}": {
+			Scopes:    []string{"orders:write"},
+			Audiences: []string{"api://payments"},
+		},
+		"GET /admin/": {
+			Groups: []string{"Admins", "Ops"},
+		},
+		"POST /admin/": {
+			Scopes: []string{"admin"},
+			Groups: []string{"Admins", "Ops"},
+		},
+	}
+
+	cases := map[string]struct {
+		method              string
+		path                string
+		defaultDeny         *bool
+		wantStatus          int
+		wantWWWAuthenticate string
+	}{
+		"allowed": {
+			method:     http.MethodGet,
+			path:       "/orders/1234",
+			wantStatus: http.StatusOK,
+		},
+		"insufficient scope": {
+			method:              http.MethodDelete,
+			path:                "/orders/1234",
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer error="insufficient_scope", error_description="The token does not have the required scopes", scope="orders:read orders:write"`,
+		},
+		"wrong audience and insufficient scope": {
+			method:              http.MethodPut,
+			path:                "/orders/1234",
+			wantStatus:          http.StatusUnauthorized,
+			wantWWWAuthenticate: `Bearer error="invalid_token", error_description="The token is invalid"`,
+		},
+		"not in group": {
+			method:              http.MethodGet,
+			path:                "/admin/users",
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer error_description="The token is not in any of the required groups"`,
+		},
+		"not in group and insufficient scope": {
+			method:              http.MethodPost,
+			path:                "/admin/users",
+			wantStatus:          http.StatusForbidden,
+			wantWWWAuthenticate: `Bearer error="insufficient_scope", error_description="The token does not have the required scopes", scope="admin"`,
+		},
+		"unlisted route/default deny": {
+			method:     http.MethodGet,
+			path:       "/customers",
+			wantStatus: http.StatusForbidden,
+		},
+		"unlisted method/default deny": {
+			method:     http.MethodPost,
+			path:       "/orders/1234",
+			wantStatus: http.StatusForbidden,
+		},
+		"unlisted route/default allow": {
+			method:      http.MethodGet,
+			path:        "/customers",
+			defaultDeny: new(bool),
+			wantStatus:  http.StatusOK,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			opts := []MiddlewareOption{}
+			if tt.defaultDeny != nil {
+				opts = append(opts, WithDefaultDeny(*tt.defaultDeny))
+			}
+
+			mw, err := issuer.verifier().PolicyMiddleware([]ClaimRule{WithExpirationRule(0)}, policies, opts...)
+			require.NoError(t, err)
+
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, ok := JWTFromContext(r.Context())
+				require.True(t, ok)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantWWWAuthenticate, rec.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestVerifier_PolicyMiddleware_invalidPattern(t *testing.T) {
+	_, err := New("https://www.example.com").PolicyMiddleware(nil, PolicyTable{
+		"GET orders": {},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `registering route "GET orders"`)
+}
+
+func Test_withGroupsRule(t *testing.T) {
+	cases := map[string]struct {
+		claims  map[string]any
+		wantErr string
+	}{
+		"in group": {
+			claims: map[string]any{"groups": []any{"Everyone", "Ops"}},
+		},
+		"not in group": {
+			claims:  map[string]any{"groups": []any{"Everyone"}},
+			wantErr: "claim 'groups' is invalid: missing any of value(s): 'Admins', 'Ops'",
+		},
+		"missing groups": {
+			claims:  map[string]any{},
+			wantErr: "claim 'groups' is invalid: missing any of value(s): 'Admins', 'Ops'",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := withGroupsRule([]string{"Admins", "Ops"}).verify(context.Background(), JWT{Claims: tt.claims})
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+			assert.ErrorIs(t, err, ErrNotInGroup)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package verifier
+
+import (
+	"context"
+	"slices"
+)
+
+const defaultTenantClaim = "tenant"
+
+// PrincipalOption is used to configure a Principal when passed into
+// NewPrincipal.
+type PrincipalOption func(*Principal)
+
+// WithTenantClaim sets the claim that Principal.Tenant returns the value of,
+// which can be a path to a nested claim such as "org.id". Defaults to
+// 'tenant'.
+func WithTenantClaim(claim string) PrincipalOption {
+	return func(p *Principal) {
+		p.tenantClaim = claim
+	}
+}
+
+// Principal is the subject of a verified JWT, which provides methods for
+// making authorization decisions without handling the claims directly. The
+// methods treat claims that are missing or that have an unexpected type as
+// if they were empty.
+type Principal struct {
+	token       JWT
+	tenantClaim string
+}
+
+// NewPrincipal creates a new Principal from a verified JWT.
+func NewPrincipal(token JWT, opts ...PrincipalOption) Principal {
+	p := Principal{
+		token:       token,
+		tenantClaim: defaultTenantClaim,
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// Token returns the JWT that the Principal was created from.
+func (p Principal) Token() JWT {
+	return p.token
+}
+
+// Subject returns the value of the 'sub' claim.
+func (p Principal) Subject() string {
+	return p.stringClaim("sub")
+}
+
+// ClientID returns the value of the 'cid' claim, which is the ID of the client
+// that requested the token.
+func (p Principal) ClientID() string {
+	return p.stringClaim("cid")
+}
+
+// UserID returns the value of the 'uid' claim, which is the ID of the user
+// that the token was issued for. It is empty for service tokens.
+func (p Principal) UserID() string {
+	return p.stringClaim("uid")
+}
+
+// IsServiceToken reports whether the token was issued to a client for itself,
+// such as with the client credentials grant, rather than to a user. This is
+// the case if it has a 'cid' claim and either its 'sub' claim equals its
+// 'cid' claim or it does not have a 'uid' claim.
+func (p Principal) IsServiceToken() bool {
+	cid := p.ClientID()
+	if cid == "" {
+		return false
+	}
+
+	return p.Subject() == cid || p.UserID() == ""
+}
+
+// Scopes returns the scopes of the token, from either the 'scp' claim or the
+// space-delimited 'scope' claim.
+func (p Principal) Scopes() []string {
+	scopes, err := parseScopes(p.token.Claims)
+	if err != nil {
+		return nil
+	}
+	return scopes
+}
+
+// HasScope reports whether the token has all of the given scopes.
+func (p Principal) HasScope(scopes ...string) bool {
+	have := p.Scopes()
+	for _, scope := range scopes {
+		if !slices.Contains(have, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyScope reports whether the token has at least one of the given scopes.
+func (p Principal) HasAnyScope(scopes ...string) bool {
+	have := p.Scopes()
+	for _, scope := range scopes {
+		if slices.Contains(have, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Groups returns the values of the 'groups' claim.
+func (p Principal) Groups() []string {
+	value, err := lookupClaim(p.token.Claims, "groups")
+	if err != nil {
+		return nil
+	}
+
+	raw, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+
+	groups := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if group, ok := v.(string); ok {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+// InGroup reports whether the 'groups' claim contains at least one of the
+// given groups.
+func (p Principal) InGroup(groups ...string) bool {
+	have := p.Groups()
+	for _, group := range groups {
+		if slices.Contains(have, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant returns the value of the claim set with WithTenantClaim.
+func (p Principal) Tenant() string {
+	return p.stringClaim(p.tenantClaim)
+}
+
+func (p Principal) stringClaim(key string) string {
+	value, err := lookupClaim(p.token.Claims, key)
+	if err != nil {
+		return ""
+	}
+
+	s, _ := value.(string)
+	return s
+}
+
+type contextKeyPrincipal struct{}
+
+// ContextWithPrincipal returns a copy of ctx that contains the given
+// Principal, which can be retrieved with PrincipalFromContext.
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, contextKeyPrincipal{}, principal)
+}
+
+// PrincipalFromContext returns the Principal stored in ctx by
+// ContextWithPrincipal, such as the Principal of the token verified by the
+// middleware returned by [Verifier.Middleware], and whether there was one. If
+// ctx only contains a JWT stored by ContextWithJWT, a Principal is created
+// from it with the default options.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	if principal, ok := ctx.Value(contextKeyPrincipal{}).(Principal); ok {
+		return principal, true
+	}
+
+	token, ok := JWTFromContext(ctx)
+	if !ok {
+		return Principal{}, false
+	}
+
+	return NewPrincipal(token), true
+}
@@ -0,0 +1,156 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrincipal(t *testing.T) {
+	cases := map[string]struct {
+		claims          map[string]any
+		opts            []PrincipalOption
+		wantSubject     string
+		wantClientID    string
+		wantService     bool
+		wantTenant      string
+		hasScope        []string
+		wantHasScope    bool
+		hasAnyScope     []string
+		wantHasAnyScope bool
+		inGroup         []string
+		wantInGroup     bool
+	}{
+		"user token": {
+			claims: map[string]any{
+				"sub":    "alice@example.com",
+				"uid":    "00u1",
+				"cid":    "0oa1",
+				"scp":    []any{"orders:read", "orders:write"},
+				"groups": []any{"Everyone", "Admins"},
+				"tenant": "acme",
+			},
+			wantSubject:     "alice@example.com",
+			wantClientID:    "0oa1",
+			wantTenant:      "acme",
+			hasScope:        []string{"orders:read", "orders:write"},
+			wantHasScope:    true,
+			hasAnyScope:     []string{"admin", "orders:read"},
+			wantHasAnyScope: true,
+			inGroup:         []string{"Admins"},
+			wantInGroup:     true,
+		},
+		"service token": {
+			claims: map[string]any{
+				"sub":   "0oa1",
+				"cid":   "0oa1",
+				"scope": "orders:read",
+			},
+			wantSubject:  "0oa1",
+			wantClientID: "0oa1",
+			wantService:  true,
+			hasScope:     []string{"orders:read", "orders:write"},
+			hasAnyScope:  []string{"orders:write"},
+			inGroup:      []string{"Admins"},
+		},
+		"service token/no uid": {
+			claims: map[string]any{
+				"sub": "service-account",
+				"cid": "0oa1",
+			},
+			wantSubject:  "service-account",
+			wantClientID: "0oa1",
+			wantService:  true,
+		},
+		"nested tenant claim": {
+			claims: map[string]any{
+				"sub": "00u1",
+				"uid": "00u1",
+				"cid": "0oa1",
+				"org": map[string]any{"id": "acme"},
+			},
+			opts:         []PrincipalOption{WithTenantClaim("org.id")},
+			wantSubject:  "00u1",
+			wantClientID: "0oa1",
+			wantTenant:   "acme",
+		},
+		"invalid claims": {
+			claims: map[string]any{
+				"sub":    1,
+				"scp":    1,
+				"groups": "Admins",
+			},
+			hasScope:    []string{"orders:read"},
+			hasAnyScope: []string{"orders:read"},
+			inGroup:     []string{"Admins"},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewPrincipal(JWT{Claims: tt.claims}, tt.opts...)
+
+			assert.Equal(t, tt.wantSubject, p.Subject())
+			assert.Equal(t, tt.wantClientID, p.ClientID())
+			assert.Equal(t, tt.wantService, p.IsServiceToken())
+			assert.Equal(t, tt.wantTenant, p.Tenant())
+			if tt.hasScope != nil {
+				assert.Equal(t, tt.wantHasScope, p.HasScope(tt.hasScope...))
+			}
+			if tt.hasAnyScope != nil {
+				assert.Equal(t, tt.wantHasAnyScope, p.HasAnyScope(tt.hasAnyScope...))
+			}
+			if tt.inGroup != nil {
+				assert.Equal(t, tt.wantInGroup, p.InGroup(tt.inGroup...))
+			}
+		})
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	token := JWT{Claims: map[string]any{"sub": "00u1", "tid": "acme"}}
+
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	p, ok := PrincipalFromContext(ContextWithJWT(context.Background(), token))
+	require.True(t, ok)
+	assert.Equal(t, "00u1", p.Subject())
+	assert.Empty(t, p.Tenant())
+
+	ctx := ContextWithPrincipal(context.Background(), NewPrincipal(token, WithTenantClaim("tid")))
+	p, ok = PrincipalFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "acme", p.Tenant())
+}
+
+func TestMiddleware_principal(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	var got Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
+		require.True(t, ok)
+		got = p
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := issuer.verifier().Middleware(nil, WithPrincipalOptions(WithTenantClaim("tid")))(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+issuer.sign(t, map[string]any{
+		"sub": "00u1",
+		"tid": "acme",
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "00u1", got.Subject())
+	assert.Equal(t, "acme", got.Tenant())
+}
@@ -0,0 +1,228 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTokenRevoked is returned by the rule created with WithNotRevokedRule when
+// the token has been revoked.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Revocation revokes the tokens of a session or of a subject.
+type Revocation struct {
+	// Subject, if set, revokes the tokens whose 'sub' or 'uid' claim equals
+	// it and that were issued at or before RevokedAt.
+	Subject string
+	// SessionID, if set, revokes the tokens whose 'sid' claim equals it.
+	SessionID string
+	// RevokedAt is when the tokens were revoked.
+	RevokedAt time.Time
+}
+
+// Revoker revokes tokens, such as when a security event is received.
+type Revoker interface {
+	Revoke(ctx context.Context, revocation Revocation) error
+}
+
+// RevocationChecker checks whether a token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, token JWT) (bool, error)
+}
+
+// RevocationList is an in-memory Revoker and RevocationChecker. Services with
+// several instances should instead implement both interfaces with a shared
+// store.
+type RevocationList struct {
+	mu        sync.Mutex
+	retention time.Duration
+	subjects  map[string]time.Time
+	sessions  map[string]time.Time
+	now       func() time.Time
+}
+
+// NewRevocationList creates a new RevocationList which forgets revocations
+// once they are older than the retention period, which should be at least the
+// maximum lifetime of the tokens that it is used to check.
+func NewRevocationList(retention time.Duration) *RevocationList {
+	return &RevocationList{
+		retention: retention,
+		subjects:  make(map[string]time.Time),
+		sessions:  make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// Revoke adds the revocation to the list.
+func (l *RevocationList) Revoke(_ context.Context, revocation Revocation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	if revocation.Subject != "" {
+		if revokedAt, ok := l.subjects[revocation.Subject]; !ok || revocation.RevokedAt.After(revokedAt) {
+			l.subjects[revocation.Subject] = revocation.RevokedAt
+		}
+	}
+	if revocation.SessionID != "" {
+		l.sessions[revocation.SessionID] = revocation.RevokedAt
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the session or the subject of the token has been
+// revoked.
+func (l *RevocationList) IsRevoked(_ context.Context, token JWT) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if sid, ok := token.Claims["sid"].(string); ok {
+		if _, revoked := l.sessions[sid]; revoked {
+			return true, nil
+		}
+	}
+
+	for _, claim := range []string{"sub", "uid"} {
+		subject, ok := token.Claims[claim].(string)
+		if !ok {
+			continue
+		}
+
+		revokedAt, revoked := l.subjects[subject]
+		if !revoked {
+			continue
+		}
+
+		iat, err := lookupTimestamp(token.Claims, "iat")
+		if err != nil || !iat.After(revokedAt) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// prune removes the revocations that are older than the retention period.
+func (l *RevocationList) prune() {
+	cutoff := l.now().Add(-l.retention)
+	for _, revocations := range []map[string]time.Time{l.subjects, l.sessions} {
+		for key, revokedAt := range revocations {
+			if revokedAt.Before(cutoff) {
+				delete(revocations, key)
+			}
+		}
+	}
+}
+
+// WithNotRevokedRule will verify that the token has not been revoked,
+// according to the given RevocationChecker, such as a RevocationList that is
+// fed by [RevokeOnSecurityEvents]. Like other context-aware rules, it is
+// verified concurrently with them.
+func WithNotRevokedRule(checker RevocationChecker) ClaimRule {
+	return WithContextTokenRule("not_revoked", func(ctx context.Context, token JWT) error {
+		revoked, err := checker.IsRevoked(ctx, token)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+		return nil
+	})
+}
+
+// RevokeOnSecurityEvents returns a function which can be passed into
+// [Verifier.SecurityEventReceiver] to revoke the tokens of the sessions and
+// subjects of the security events that it receives:
+//
+//   - A session revoked event revokes the tokens of its session, or of its
+//     subject if it does not identify a session.
+//   - A credential change event that is not the creation of a credential,
+//     and account disabled, account purged, credential compromise and
+//     sessions revoked events revoke the tokens of their subject.
+//
+// Other events are ignored. A subject is identified by its email address,
+// its 'sub', its ID, its phone number or its URI, depending on its format.
+func RevokeOnSecurityEvents(revoker Revoker) func(ctx context.Context, set SecurityEventToken) error {
+	return func(ctx context.Context, set SecurityEventToken) error {
+		revokedAt := time.Now()
+		if iat, err := lookupTimestamp(set.JWT.Claims, "iat"); err == nil {
+			revokedAt = iat
+		}
+
+		for _, event := range set.Events {
+			revocation, ok := eventRevocation(event, revokedAt)
+			if !ok {
+				continue
+			}
+
+			if err := revoker.Revoke(ctx, revocation); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// eventRevocation returns the revocation for a security event, which is
+// revoked at the time of the event if it has one or at revokedAt otherwise,
+// and false if the event does not revoke any tokens.
+func eventRevocation(event SecurityEvent, revokedAt time.Time) (Revocation, bool) {
+	var revocation Revocation
+
+	switch e := event.(type) {
+	case *SessionRevokedEvent:
+		revocation = Revocation{Subject: subjectID(e.Subject)}
+		if e.Subject.Session != nil {
+			revocation = Revocation{SessionID: subjectID(*e.Subject.Session)}
+		}
+	case *CredentialChangeEvent:
+		if e.ChangeType == "create" {
+			return Revocation{}, false
+		}
+		revocation = Revocation{Subject: subjectID(e.Subject)}
+	case *AccountDisabledEvent, *AccountPurgedEvent, *CredentialCompromiseEvent, *SessionsRevokedEvent:
+		revocation = Revocation{Subject: subjectID(e.EventSubject())}
+	default:
+		return Revocation{}, false
+	}
+
+	if revocation.Subject == "" && revocation.SessionID == "" {
+		return Revocation{}, false
+	}
+
+	revocation.RevokedAt = revokedAt
+	if caep, ok := event.(interface{ timestamp() int64 }); ok && caep.timestamp() != 0 {
+		revocation.RevokedAt = time.Unix(caep.timestamp(), 0)
+	}
+
+	return revocation, true
+}
+
+// subjectID returns the identifier of a simple subject, or of the user of a
+// complex subject.
+func subjectID(subject SubjectIdentifier) string {
+	switch subject.Format {
+	case "email":
+		return subject.Email
+	case "iss_sub":
+		return subject.Subject
+	case "opaque":
+		return subject.ID
+	case "phone_number":
+		return subject.PhoneNumber
+	case "uri":
+		return subject.URI
+	}
+
+	if subject.User != nil {
+		return subjectID(*subject.User)
+	}
+
+	return ""
+}
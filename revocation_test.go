@@ -0,0 +1,154 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevocationList(t *testing.T) {
+	now := time.Now()
+
+	list := NewRevocationList(time.Hour)
+	require.NoError(t, list.Revoke(context.Background(), Revocation{SessionID: "sess-1", RevokedAt: now}))
+	require.NoError(t, list.Revoke(context.Background(), Revocation{Subject: "00u1", RevokedAt: now}))
+	require.NoError(t, list.Revoke(context.Background(), Revocation{
+		Subject:   "00u2",
+		RevokedAt: now.Add(-2 * time.Hour),
+	}))
+
+	cases := map[string]struct {
+		claims      map[string]any
+		wantRevoked bool
+	}{
+		"revoked session": {
+			claims:      map[string]any{"sid": "sess-1", "sub": "00u3", "iat": float64(now.Add(time.Minute).Unix())},
+			wantRevoked: true,
+		},
+		"revoked subject": {
+			claims:      map[string]any{"sub": "00u1", "iat": float64(now.Add(-time.Minute).Unix())},
+			wantRevoked: true,
+		},
+		"revoked subject/uid": {
+			claims:      map[string]any{"sub": "alice@example.com", "uid": "00u1", "iat": float64(now.Add(-time.Minute).Unix())},
+			wantRevoked: true,
+		},
+		"revoked subject/issued after revocation": {
+			claims: map[string]any{"sub": "00u1", "iat": float64(now.Add(time.Minute).Unix())},
+		},
+		"revoked subject/no iat": {
+			claims:      map[string]any{"sub": "00u1"},
+			wantRevoked: true,
+		},
+		"revocation past retention": {
+			claims: map[string]any{"sub": "00u2", "iat": float64(now.Add(-3 * time.Hour).Unix())},
+		},
+		"not revoked": {
+			claims: map[string]any{"sid": "sess-2", "sub": "00u3"},
+		},
+	}
+
+	// Trigger pruning of the revocation that is past the retention period.
+	require.NoError(t, list.Revoke(context.Background(), Revocation{}))
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			revoked, err := list.IsRevoked(context.Background(), JWT{Claims: tt.claims})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRevoked, revoked)
+		})
+	}
+}
+
+func TestRevocationList_prune(t *testing.T) {
+	now := time.Date(2021, time.September, 11, 14, 0, 0, 0, time.UTC)
+
+	list := NewRevocationList(time.Hour)
+	list.now = func() time.Time { return now }
+
+	require.NoError(t, list.Revoke(context.Background(), Revocation{Subject: "00u1", RevokedAt: now.Add(-30 * time.Minute)}))
+	require.NoError(t, list.Revoke(context.Background(), Revocation{Subject: "00u2", RevokedAt: now.Add(-2 * time.Hour)}))
+
+	// Trigger pruning, which must use the clock of the list.
+	require.NoError(t, list.Revoke(context.Background(), Revocation{}))
+
+	assert.Contains(t, list.subjects, "00u1")
+	assert.NotContains(t, list.subjects, "00u2")
+}
+
+func TestRevokeOnSecurityEvents(t *testing.T) {
+	issuer := newTestIssuer(t)
+	v := issuer.verifier()
+	list := NewRevocationList(time.Hour)
+
+	receiver := v.SecurityEventReceiver("https://rp.example.com", RevokeOnSecurityEvents(list))
+
+	set := issuer.sign(t, jwt.MapClaims{
+		"iss": issuer.URL,
+		"aud": "https://rp.example.com",
+		"iat": time.Now().Unix(),
+		"jti": "4d3559ec67504aaba65d40b0363faad8",
+		"events": map[string]any{
+			EventTypeSessionRevoked: map[string]any{
+				"subject": map[string]any{
+					"user":    map[string]any{"format": "email", "email": "alice@example.com"},
+					"session": map[string]any{"format": "opaque", "id": "sess-1"},
+				},
+			},
+			EventTypeAccountDisabled: map[string]any{
+				"subject": map[string]any{"format": "iss_sub", "iss": issuer.URL, "sub": "00u2"},
+			},
+			EventTypeCredentialChange: map[string]any{
+				"subject":         map[string]any{"format": "email", "email": "carol@example.com"},
+				"credential_type": "fido2-roaming",
+				"change_type":     "create",
+			},
+		},
+	}, map[string]any{"typ": "secevent+jwt"})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(set))
+	req.Header.Set("Content-Type", "application/secevent+jwt")
+	rec := httptest.NewRecorder()
+	receiver.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	issuedAt := time.Now().Add(-time.Minute).Unix()
+	cases := map[string]struct {
+		claims      jwt.MapClaims
+		wantRevoked bool
+	}{
+		"revoked session": {
+			claims:      jwt.MapClaims{"sub": "alice@example.com", "sid": "sess-1", "iat": issuedAt},
+			wantRevoked: true,
+		},
+		"other session of user": {
+			claims: jwt.MapClaims{"sub": "alice@example.com", "sid": "sess-2", "iat": issuedAt},
+		},
+		"disabled account": {
+			claims:      jwt.MapClaims{"sub": "00u2", "iat": issuedAt},
+			wantRevoked: true,
+		},
+		"created credential": {
+			claims: jwt.MapClaims{"sub": "carol@example.com", "iat": issuedAt},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.ParseAndVerify(context.Background(), issuer.sign(t, tt.claims), WithNotRevokedRule(list))
+			if tt.wantRevoked {
+				require.Error(t, err)
+				assert.ErrorIs(t, err, ErrTokenRevoked)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
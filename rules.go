@@ -1,10 +1,14 @@
 package verifier
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,29 +23,515 @@ import (
 // another object, then value will be a map[string]any.
 type Rule func(value any) error
 
+// TokenRule receives the entire parsed JWT, including all of its claims and
+// its header, and returns a non-nil error if the token is not valid. It is
+// used to verify relationships between claims, such as requiring the value
+// of one claim to equal the value of another.
+//
+// The claims follow the same rules as the value passed into a [Rule].
+type TokenRule func(token JWT) error
+
+// ContextRule is like a [Rule], but it also receives the context that was
+// passed into [Verifier.ParseAndVerify]. It can be used to verify the value
+// of a claim using lookups that should honor the context, such as querying a
+// database or a feature flag service.
+//
+// Since an error may mean either that the value is not valid or that the
+// lookup failed, an error that means the value is not valid should be a
+// [ValueError] or wrap [ErrRuleFailed]. Other errors fail [Not] and [If]
+// rather than being treated as the rule not passing.
+type ContextRule func(ctx context.Context, value any) error
+
+// ContextTokenRule is like a [TokenRule], but it also receives the context
+// that was passed into [Verifier.ParseAndVerify]. Its errors are treated in
+// the same way as those of a [ContextRule].
+type ContextTokenRule func(ctx context.Context, token JWT) error
+
 // ClaimRule contains the JWT claim key and a function to verify the value
 // of the claim.
+//
+// Key is usually the name of a top-level claim, but nested claims can be
+// verified using a path in dot notation, such as "tenant.id" or "groups.0",
+// or a JSON Pointer as described in RFC 6901, such as "/cnf/jkt". If a
+// top-level claim exists whose name is exactly Key, it takes precedence over
+// a path in dot notation.
+//
+// By default the claim is required, so the rule fails if the claim is not
+// present in the token. If Rule is nil then the rule only checks that the
+// claim is present. See [WithRequiredClaimRule], [Optional] and
+// [WithForbiddenClaimRule].
 type ClaimRule struct {
 	Key  string
 	Rule Rule
+
+	// Optional, if true, means that the rule passes if the claim is not
+	// present, and that Rule is only called if it is.
+	Optional bool
+	// Forbidden, if true, means that the rule fails if the claim is present.
+	// Rule is not called.
+	Forbidden bool
+
+	// token, if set, is called with the entire parsed token instead of
+	// calling Rule with the value of the claim. This is used by rules which
+	// need to inspect more than one claim, so it is also responsible for
+	// checking whether the claim(s) it needs are present. If Key is empty then
+	// the error is returned as-is, which is used by rules that combine other
+	// rules.
+	token ContextTokenRule
+	// contextRule, if set, is called with the value of the claim instead of
+	// Rule.
+	contextRule ContextRule
+	// aliases are other claims that the rule reads in place of Key, such as
+	// 'scope' for the scope rules. If any of them is present, the claim is
+	// considered to be present by Optional and Forbidden.
+	aliases []string
+	// name is the name of the rule, which is used to describe rules that do
+	// not have a key.
+	name string
+	// timeout, if non-zero, limits how long the rule may take to verify the
+	// token.
+	timeout time.Duration
+	// contextAware is true if the rule, or any of the rules it combines, uses
+	// the context. Such rules are verified concurrently with each other by
+	// ParseAndVerify since they may perform lookups.
+	contextAware bool
+	// lookup is true for rules created with WithContextRule and
+	// WithContextTokenRule, whose errors may be caused by a failed lookup
+	// rather than by the token.
+	lookup bool
+}
+
+// verify verifies the token using the rule. If the rule fails, the error is
+// always a *ClaimFailure.
+func (r ClaimRule) verify(ctx context.Context, token JWT) error {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	v, lookupErr := lookupClaim(token.Claims, r.Key)
+	ok := lookupErr == nil
+
+	if done, err := r.checkPresence(token.Claims, ok); done {
+		return err
+	}
+
+	if r.token != nil {
+		if err := r.token(ctx, token); err != nil {
+			return r.failure(ctx, err)
+		}
+		return nil
+	}
+
+	if !ok {
+		return &ClaimFailure{
+			Key:    r.Key,
+			Rule:   r.name,
+			Reason: strings.TrimPrefix(lookupErr.Error(), fmt.Sprintf("claim '%s' ", r.Key)),
+			Err:    lookupErr,
+			msg:    lookupErr.Error(),
+		}
+	}
+
+	var err error
+	switch {
+	case r.contextRule != nil:
+		err = r.contextRule(ctx, v)
+	case r.Rule != nil:
+		err = r.Rule(v)
+	}
+
+	if err != nil {
+		return r.failure(ctx, err)
+	}
+
+	return nil
+}
+
+// checkPresence applies Optional and Forbidden to the rule given whether its
+// claim is present. It returns true if that decides the rule without
+// verifying the value of the claim, along with the result of the rule.
+func (r ClaimRule) checkPresence(claims map[string]any, ok bool) (bool, error) {
+	// Optional and Forbidden have no meaning for rules that combine other
+	// rules, since those do not have a key of their own.
+	if r.Key == "" {
+		return false, nil
+	}
+
+	presentKey, present := r.presentKey(claims, ok)
+	switch {
+	case r.Forbidden && present:
+		return true, &ClaimFailure{
+			Key:    r.Key,
+			Rule:   r.name,
+			Reason: ErrForbiddenClaim.Error(),
+			Err:    ErrForbiddenClaim,
+			msg:    fmt.Sprintf("claim '%s' must not be present", presentKey),
+		}
+	case r.Forbidden:
+		return true, nil
+	case r.Optional && !present:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// presentKey returns the key of the rule, or the first of its aliases that is
+// present in the claims, and whether any of them is present.
+func (r ClaimRule) presentKey(claims map[string]any, ok bool) (string, bool) {
+	if ok {
+		return r.Key, true
+	}
+
+	for _, alias := range r.aliases {
+		if _, err := lookupClaim(claims, alias); err == nil {
+			return alias, true
+		}
+	}
+
+	return r.Key, false
+}
+
+// failure returns a *ClaimFailure for an error returned by the rule.
+func (r ClaimRule) failure(ctx context.Context, err error) *ClaimFailure {
+	if !isIndeterminate(err) && r.indeterminate(ctx, err) {
+		err = &sentinelError{sentinel: errIndeterminate, err: err}
+	}
+
+	if r.Key == "" {
+		return &ClaimFailure{
+			Rule:   r.name,
+			Reason: err.Error(),
+			Err:    err,
+			msg:    err.Error(),
+		}
+	}
+
+	failure := &ClaimFailure{
+		Key:    r.Key,
+		Rule:   r.name,
+		Reason: err.Error(),
+		Err:    err,
+		msg:    fmt.Sprintf("claim '%s' is invalid: %s", r.Key, err.Error()),
+	}
+
+	var valueErr *ValueError
+	if errors.As(err, &valueErr) {
+		failure.Expected = valueErr.Expected
+		failure.Got = valueErr.Got
+	}
+
+	return failure
+}
+
+// indeterminate reports whether an error returned by the rule means that the
+// rule could not decide whether the token is valid, such as when a lookup
+// failed or the context was done, rather than that the token failed it.
+func (r ClaimRule) indeterminate(ctx context.Context, err error) bool {
+	switch {
+	case ctx.Err() != nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case !r.lookup:
+		return false
+	}
+
+	var valueErr *ValueError
+	return !errors.As(err, &valueErr) && !errors.Is(err, ErrRuleFailed)
+}
+
+// isIndeterminate reports whether the error is the failure of a rule, or of a
+// rule that it combines, which could not decide whether the token is valid.
+func isIndeterminate(err error) bool {
+	return errors.Is(err, errIndeterminate)
+}
+
+// verifyRules verifies the token using all of the given rules and returns
+// a *ValidationError containing the failures of the rules that failed, in
+// the same order as the rules, or nil if all of them passed. Context-aware
+// rules are verified concurrently, and if one of them panics it fails instead
+// of crashing the process.
+func verifyRules(ctx context.Context, token JWT, rules []ClaimRule) *ValidationError {
+	results := make([]error, len(rules))
+
+	var wg sync.WaitGroup
+	for i, rule := range rules {
+		if !rule.contextAware {
+			results[i] = rule.verify(ctx, token)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A panic in a goroutine cannot be recovered by the caller, so it
+			// would otherwise crash the process.
+			defer func() {
+				if p := recover(); p != nil {
+					results[i] = rule.failure(ctx, &sentinelError{
+						sentinel: errIndeterminate,
+						err:      fmt.Errorf("rule panicked: %v", p),
+					})
+				}
+			}()
+			results[i] = rule.verify(ctx, token)
+		}()
+	}
+	wg.Wait()
+
+	failures := make([]ClaimFailure, 0)
+	for _, err := range results {
+		if err != nil {
+			failures = append(failures, *err.(*ClaimFailure))
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Failures: failures}
+}
+
+// WithTokenRule returns a ClaimRule which verifies the entire token using
+// the given TokenRule. The name is used to identify the rule in the error
+// returned by [Verifier.ParseAndVerify] if the rule fails. For instance:
+//
+//	WithTokenRule("sub matches uid", func(token JWT) error {
+//		if token.Claims["sub"] != token.Claims["uid"] {
+//			return errors.New("'sub' does not equal 'uid'")
+//		}
+//		return nil
+//	})
+func WithTokenRule(name string, rule TokenRule) ClaimRule {
+	return withTokenRule(name, func(_ context.Context, token JWT) error {
+		return rule(token)
+	})
+}
+
+// WithContextRule returns a ClaimRule which verifies the value of the given
+// claim using a ContextRule. Context-aware rules are verified concurrently
+// with each other by [Verifier.ParseAndVerify], so they should not depend on
+// the order in which rules are verified. See also [WithTimeout].
+func WithContextRule(claim string, rule ContextRule) ClaimRule {
+	return ClaimRule{
+		Key:          claim,
+		contextRule:  rule,
+		contextAware: true,
+		lookup:       true,
+	}
+}
+
+// WithContextTokenRule returns a ClaimRule which verifies the entire token
+// using a ContextTokenRule. It is otherwise the same as [WithTokenRule], and
+// is verified concurrently in the same way as [WithContextRule].
+func WithContextTokenRule(name string, rule ContextTokenRule) ClaimRule {
+	r := withTokenRule(name, rule)
+	r.contextAware = true
+	r.lookup = true
+	return r
+}
+
+// WithTimeout returns a copy of the given rule which cancels the context
+// passed into it after the given timeout. This is only useful for rules that
+// use the context, such as those created with [WithContextRule].
+func WithTimeout(rule ClaimRule, timeout time.Duration) ClaimRule {
+	rule.timeout = timeout
+	return rule
+}
+
+func withTokenRule(name string, rule ContextTokenRule) ClaimRule {
+	return ClaimRule{
+		name: name,
+		token: func(ctx context.Context, token JWT) error {
+			if err := rule(ctx, token); err != nil {
+				return fmt.Errorf("rule '%s' failed: %w", name, err)
+			}
+			return nil
+		},
+	}
+}
+
+// WithClaimsEqualRule will verify that the claims a and b are both present
+// and have equal values. For instance, WithClaimsEqualRule("azp", "cid") will
+// verify that the 'azp' claim equals the 'cid' claim.
+func WithClaimsEqualRule(a, b string) ClaimRule {
+	return WithTokenRule("claims_equal", func(token JWT) error {
+		valueA, err := lookupClaim(token.Claims, a)
+		if err != nil {
+			return err
+		}
+
+		valueB, err := lookupClaim(token.Claims, b)
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(valueA, valueB) {
+			return fmt.Errorf("claim '%s' ('%v') does not equal claim '%s' ('%v')", a, valueA, b, valueB)
+		}
+
+		return nil
+	})
+}
+
+// WithMaxLifetimeRule will verify that the difference between the 'exp' and
+// 'iat' claims of the token is no more than maxLifetime. The timestamps may be
+// either numbers or json.Numbers.
+func WithMaxLifetimeRule(maxLifetime time.Duration) ClaimRule {
+	return WithTokenRule("max_lifetime", func(token JWT) error {
+		exp, err := lookupTimestamp(token.Claims, "exp")
+		if err != nil {
+			return err
+		}
+
+		iat, err := lookupTimestamp(token.Claims, "iat")
+		if err != nil {
+			return err
+		}
+
+		if lifetime := exp.Sub(iat); lifetime > maxLifetime {
+			return fmt.Errorf("token lifetime of %s exceeds %s", lifetime, maxLifetime)
+		}
+
+		return nil
+	})
+}
+
+// WithRequiredClaimRule will verify that the given claim is present, without
+// verifying its value.
+func WithRequiredClaimRule(claim string) ClaimRule {
+	return ClaimRule{Key: claim, name: "required"}
+}
+
+// WithForbiddenClaimRule will verify that the given claim is not present. For
+// instance, WithForbiddenClaimRule("act") can be used to reject tokens that
+// were obtained through delegation.
+//
+// Since the scope rules read the 'scope' claim if there is no 'scp' claim,
+// WithForbiddenClaimRule("scp") also forbids the 'scope' claim.
+func WithForbiddenClaimRule(claim string) ClaimRule {
+	return ClaimRule{Key: claim, name: "forbidden", Forbidden: true, aliases: claimAliases(claim)}
+}
+
+// Optional returns a copy of the given rule which passes if the claim is not
+// present in the token, and otherwise verifies the claim in the same way as
+// the given rule. For instance, the following will verify the value of the
+// 'tenant' claim only if the token has one:
+//
+//	Optional(WithCustomClaimExactMatchRule("tenant", "acme"))
+//
+// The scope rules are skipped only if the token has neither a 'scp' nor a
+// 'scope' claim, since they read the scopes from either of them.
+func Optional(rule ClaimRule) ClaimRule {
+	rule.Optional = true
+	return rule
 }
 
 // WithIssuerRule will verify that the value of the 'iss' claim equals the
-// given value.
+// given value. If it does not, the error explains common mistakes in the
+// configuration of the issuer, such as a trailing slash or the ID of a
+// different authorization server.
 func WithIssuerRule(wantIss string) ClaimRule {
-	return WithCustomClaimExactMatchRule("iss", wantIss)
+	return ClaimRule{
+		Key:  "iss",
+		name: "issuer",
+		Rule: func(value any) error {
+			got, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("expected a %T but got a %T", got, value)
+			}
+
+			if got != wantIss {
+				return issuerMismatch(wantIss, got)
+			}
+
+			return nil
+		},
+	}
 }
 
 // WithAudienceRule will verify that the value of the 'aud' claim equals the
-// given value.
+// given value. If the 'aud' claim is an array, it will verify that the array
+// contains the given value.
 func WithAudienceRule(wantAud string) ClaimRule {
-	return WithCustomClaimExactMatchRule("aud", wantAud)
+	return WithAudiencesRule(wantAud)
+}
+
+// WithAudiencesRule will verify that at least one of the audiences in the
+// 'aud' claim is one of the accepted audiences. The 'aud' claim may either be
+// a single string or an array of strings, as permitted by RFC 7519.
+//
+// When the token may have multiple audiences, such as with OIDC ID tokens,
+// this should be used together with [WithAuthorizedPartyRule].
+func WithAudiencesRule(accepted ...string) ClaimRule {
+	return ClaimRule{
+		Key:  "aud",
+		name: "audience",
+		Rule: func(value any) error {
+			got, err := parseAudiences(value)
+			if err != nil {
+				return err
+			}
+
+			for _, aud := range got {
+				if slices.Contains(accepted, aud) {
+					return nil
+				}
+			}
+
+			if _, ok := value.(string); ok && len(accepted) == 1 {
+				return &ValueError{Expected: accepted[0], Got: got[0]}
+			}
+
+			return &ValueError{
+				Expected: accepted,
+				Got:      got,
+				msg:      fmt.Sprintf("expected one of %s but got %s", quoteJoin(accepted), quoteJoin(got)),
+			}
+		},
+	}
+}
+
+// WithAuthorizedPartyRule will verify that, if the 'azp' claim is present,
+// its value equals the given client ID. If the 'aud' claim contains more
+// than one audience then the 'azp' claim is required, as described in
+// section 3.1.3.7 of the OIDC Core specification.
+func WithAuthorizedPartyRule(clientID string) ClaimRule {
+	return ClaimRule{
+		Key:  "azp",
+		name: "authorized_party",
+		token: func(_ context.Context, token JWT) error {
+			azp, ok := token.Claims["azp"]
+			if !ok {
+				aud, err := parseAudiences(token.Claims["aud"])
+				if err == nil && len(aud) > 1 {
+					return errors.New("claim is required when 'aud' contains multiple audiences")
+				}
+				return nil
+			}
+
+			got, ok := azp.(string)
+			if !ok {
+				return fmt.Errorf("expected a %T but got a %T", got, azp)
+			}
+
+			if got != clientID {
+				return &ValueError{Expected: clientID, Got: got}
+			}
+
+			return nil
+		},
+	}
 }
 
 // WithClientIDRule will verify that the value of the 'cid' claim equals the
 // given value.
 func WithClientIDRule(wantCid string) ClaimRule {
-	return WithCustomClaimExactMatchRule("cid", wantCid)
+	rule := WithCustomClaimExactMatchRule("cid", wantCid)
+	rule.name = "client_id"
+	return rule
 }
 
 // WithExpirationRule returns a ClaimRule which will check if the value
@@ -51,7 +541,7 @@ func WithClientIDRule(wantCid string) ClaimRule {
 // This should only be called if the [WithJSONNumber] is not used with
 // [Verifier.ParseAndVerify].
 func WithExpirationRule(leeway int) ClaimRule {
-	return withTimestampRule("exp", leeway, false, time.Since, "token is expired")
+	return withTimestampRule("exp", leeway, false, time.Since, ErrTokenExpired)
 }
 
 // WithExpirationRuleJSONNumber returns a ClaimRule which will check if the
@@ -61,7 +551,7 @@ func WithExpirationRule(leeway int) ClaimRule {
 // This should only be called if the [WithJSONNumber] is used with
 // [Verifier.ParseAndVerify].
 func WithExpirationRuleJSONNumber(leeway int) ClaimRule {
-	return withTimestampRule("exp", leeway, true, time.Since, "token is expired")
+	return withTimestampRule("exp", leeway, true, time.Since, ErrTokenExpired)
 }
 
 // WithIssuedAtRule returns a ClaimRule which will check if the value
@@ -71,7 +561,7 @@ func WithExpirationRuleJSONNumber(leeway int) ClaimRule {
 // This should only be called if the [WithJSONNumber] is not used with
 // [Verifier.ParseAndVerify].
 func WithIssuedAtRule(leeway int) ClaimRule {
-	return withTimestampRule("iat", leeway, false, time.Since, "token was issued in the future")
+	return withTimestampRule("iat", leeway, false, time.Since, ErrTokenIssuedInFuture)
 }
 
 // WithIssuedAtRuleJSONNumber returns a ClaimRule which will check if the value
@@ -81,34 +571,39 @@ func WithIssuedAtRule(leeway int) ClaimRule {
 // This should only be called if the [WithJSONNumber] is used with
 // [Verifier.ParseAndVerify].
 func WithIssuedAtRuleJSONNumber(leeway int) ClaimRule {
-	return withTimestampRule("iat", leeway, true, time.Since, "token was issued in the future")
+	return withTimestampRule("iat", leeway, true, time.Since, ErrTokenIssuedInFuture)
 }
 
 // WithIssuerRule will verify that the value of the 'iss' claim equals the
-// issuer that the Verifier was initialized with.
+// issuer that the Verifier was initialized with, or one of the aliases set
+// with WithIssuerAliases.
 func (j Verifier) WithIssuerRule() ClaimRule {
-	return WithIssuerRule(j.issuer)
+	if len(j.issuerAliases) == 0 {
+		return WithIssuerRule(j.issuer)
+	}
+	return withIssuersRule(j.issuers())
 }
 
 // WithExpirationRule returns a ClaimRule which will check if the value
 // of the 'exp' claim is a timestamp is more than leeway seconds old, and if
 // so it will return an error.
 func (j Verifier) WithExpirationRule(leeway int) ClaimRule {
-	return withTimestampRule("exp", leeway, j.useJSONNumber, time.Since, "token is expired")
+	return withTimestampRule("exp", leeway, j.useJSONNumber, time.Since, ErrTokenExpired)
 }
 
 // WithIssuedAtRule returns a ClaimRule which will check if the value
 // of the 'iat' claim is a timestamp is more than leeway seconds in the future,
 // and if so it will return an error.
 func (j Verifier) WithIssuedAtRule(leeway int) ClaimRule {
-	return withTimestampRule("iat", leeway, j.useJSONNumber, time.Until, "token was issued in the future")
+	return withTimestampRule("iat", leeway, j.useJSONNumber, time.Until, ErrTokenIssuedInFuture)
 }
 
 // WithCustomClaimExactMatchRule will check that the value of the given
 // claim equals the given value exactly.
 func WithCustomClaimExactMatchRule[T comparable](claim string, wantValue T) ClaimRule {
 	return ClaimRule{
-		Key: claim,
+		Key:  claim,
+		name: "exact_match",
 		Rule: func(value any) error {
 			got, ok := value.(T)
 			if !ok {
@@ -116,7 +611,7 @@ func WithCustomClaimExactMatchRule[T comparable](claim string, wantValue T) Clai
 			}
 
 			if wantValue != got {
-				return fmt.Errorf("expected '%v' but got '%v'", wantValue, got)
+				return &ValueError{Expected: wantValue, Got: got}
 			}
 
 			return nil
@@ -128,7 +623,8 @@ func WithCustomClaimExactMatchRule[T comparable](claim string, wantValue T) Clai
 // are presen in the claim, whose value should be an array of the same type.
 func WithCustomClaimContainsRule[T comparable](claim string, wantValues []T) ClaimRule {
 	return ClaimRule{
-		Key: claim,
+		Key:  claim,
+		name: "contains",
 		Rule: func(value any) error {
 			raw, ok := value.([]any)
 			if !ok {
@@ -157,13 +653,44 @@ func WithCustomClaimContainsRule[T comparable](claim string, wantValues []T) Cla
 				return nil
 			}
 
-			var builder strings.Builder
-			builder.WriteString(fmt.Sprintf("'%v'", missingValues[0]))
-			for _, value := range missingValues[1:] {
-				builder.WriteString(fmt.Sprintf(", '%v'", value))
+			return &ValueError{
+				Expected: wantValues,
+				Got:      raw,
+				msg:      fmt.Sprintf("missing value(s): %s", quoteJoin(missingValues)),
+			}
+		},
+	}
+}
+
+// WithCustomClaimContainsAnyRule will check that at least one of the values
+// in wantValues is present in the claim, whose value should be an array of
+// the same type.
+func WithCustomClaimContainsAnyRule[T comparable](claim string, wantValues []T) ClaimRule {
+	return ClaimRule{
+		Key:  claim,
+		name: "contains_any",
+		Rule: func(value any) error {
+			raw, ok := value.([]any)
+			if !ok {
+				return fmt.Errorf("expected an array but got a %T", value)
 			}
 
-			return fmt.Errorf("missing value(s): %s", builder.String())
+			for _, v := range raw {
+				claim, ok := v.(T)
+				if !ok {
+					return fmt.Errorf("value of array element is not a %T", claim)
+				}
+
+				if slices.Contains(wantValues, claim) {
+					return nil
+				}
+			}
+
+			return &ValueError{
+				Expected: wantValues,
+				Got:      raw,
+				msg:      fmt.Sprintf("missing any of value(s): %s", quoteJoin(wantValues)),
+			}
 		},
 	}
 }
@@ -173,10 +700,16 @@ func withTimestampRule(
 	leeway int,
 	useJSONNumber bool,
 	comparer func(time.Time) time.Duration,
-	errMsg string,
+	failErr error,
 ) ClaimRule {
+	name := "expiration"
+	if claim == "iat" {
+		name = "issued_at"
+	}
+
 	return ClaimRule{
-		Key: claim,
+		Key:  claim,
+		name: name,
 		Rule: func(value any) error {
 			ts, err := parseTimestamp(value, useJSONNumber)
 			if err != nil {
@@ -184,7 +717,7 @@ func withTimestampRule(
 			}
 
 			if comparer(ts) > time.Second*time.Duration(leeway) {
-				return errors.New(errMsg)
+				return failErr
 			}
 
 			return nil
@@ -192,6 +725,24 @@ func withTimestampRule(
 	}
 }
 
+// lookupTimestamp looks up the claim with the given key and parses it as a
+// timestamp, accepting both numbers and json.Numbers.
+func lookupTimestamp(claims map[string]any, key string) (time.Time, error) {
+	value, err := lookupClaim(claims, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	_, useJSONNumber := value.(json.Number)
+
+	ts, err := parseTimestamp(value, useJSONNumber)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("claim '%s' is invalid: %w", key, err)
+	}
+
+	return ts, nil
+}
+
 func parseTimestamp(value any, useJSONNumber bool) (time.Time, error) {
 	if useJSONNumber {
 		exp, ok := value.(json.Number)
@@ -214,3 +765,36 @@ func parseTimestamp(value any, useJSONNumber bool) (time.Time, error) {
 
 	return time.Unix(int64(exp), 0).UTC(), nil
 }
+
+func parseAudiences(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []any:
+		audiences := make([]string, 0, len(v))
+		for _, raw := range v {
+			aud, ok := raw.(string)
+			if !ok {
+				return nil, fmt.Errorf("value of array element is not a %T", aud)
+			}
+			audiences = append(audiences, aud)
+		}
+		return audiences, nil
+	default:
+		return nil, fmt.Errorf("expected a string or an array but got a %T", value)
+	}
+}
+
+func quoteJoin[T any](values []T) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("'%v'", values[0]))
+	for _, value := range values[1:] {
+		builder.WriteString(fmt.Sprintf(", '%v'", value))
+	}
+
+	return builder.String()
+}
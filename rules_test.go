@@ -1,6 +1,11 @@
 package verifier
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -209,3 +214,436 @@ func TestWithCustomClaimContainsRule(t *testing.T) {
 		})
 	}
 }
+
+func TestWithAudiencesRule(t *testing.T) {
+	cases := map[string]struct {
+		claims   map[string]any
+		accepted []string
+		wantErr  string
+	}{
+		"wrong type": {
+			claims: map[string]any{
+				"aud": float64(1234),
+			},
+			accepted: []string{"foo"},
+			wantErr:  "expected a string or an array but got a float64",
+		},
+		"wrong array element type": {
+			claims: map[string]any{
+				"aud": []any{"foo", float64(1234)},
+			},
+			accepted: []string{"foo"},
+			wantErr:  "value of array element is not a string",
+		},
+		"string/fails validation": {
+			claims: map[string]any{
+				"aud": "bar",
+			},
+			accepted: []string{"foo"},
+			wantErr:  "expected 'foo' but got 'bar'",
+		},
+		"string/fails validation with multiple accepted": {
+			claims: map[string]any{
+				"aud": "bar",
+			},
+			accepted: []string{"foo", "hello"},
+			wantErr:  "expected one of 'foo', 'hello' but got 'bar'",
+		},
+		"string/passes validation": {
+			claims: map[string]any{
+				"aud": "hello",
+			},
+			accepted: []string{"foo", "hello"},
+		},
+		"array/fails validation": {
+			claims: map[string]any{
+				"aud": []any{"bar", "world"},
+			},
+			accepted: []string{"foo"},
+			wantErr:  "expected one of 'foo' but got 'bar', 'world'",
+		},
+		"array/passes validation": {
+			claims: map[string]any{
+				"aud": []any{"bar", "world"},
+			},
+			accepted: []string{"foo", "world"},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			rule := WithAudiencesRule(tt.accepted...)
+			require.Equal(t, rule.Key, "aud")
+
+			err := rule.Rule(tt.claims[rule.Key])
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithAuthorizedPartyRule(t *testing.T) {
+	cases := map[string]struct {
+		claims  map[string]any
+		wantErr string
+	}{
+		"single audience/no azp": {
+			claims: map[string]any{
+				"aud": "foo",
+			},
+		},
+		"multiple audiences/no azp": {
+			claims: map[string]any{
+				"aud": []any{"foo", "bar"},
+			},
+			wantErr: "claim 'azp' is invalid: claim is required when 'aud' contains multiple audiences",
+		},
+		"wrong type": {
+			claims: map[string]any{
+				"aud": "foo",
+				"azp": float64(1234),
+			},
+			wantErr: "claim 'azp' is invalid: expected a string but got a float64",
+		},
+		"fails validation": {
+			claims: map[string]any{
+				"aud": []any{"foo", "bar"},
+				"azp": "bar",
+			},
+			wantErr: "claim 'azp' is invalid: expected 'foo' but got 'bar'",
+		},
+		"passes validation": {
+			claims: map[string]any{
+				"aud": []any{"foo", "bar"},
+				"azp": "foo",
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			rule := WithAuthorizedPartyRule("foo")
+			require.Equal(t, rule.Key, "azp")
+
+			err := rule.verify(context.Background(), JWT{Claims: tt.claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestClaimRule_presence(t *testing.T) {
+	claims := map[string]any{
+		"sub":    "user",
+		"tenant": "acme",
+		"act":    map[string]any{"sub": "admin"},
+		"scope":  "read",
+	}
+
+	cases := map[string]struct {
+		rule    ClaimRule
+		wantErr string
+	}{
+		"required/present": {
+			rule: WithRequiredClaimRule("sub"),
+		},
+		"required/missing": {
+			rule:    WithRequiredClaimRule("uid"),
+			wantErr: "claim 'uid' not found",
+		},
+		"optional/missing": {
+			rule: Optional(WithCustomClaimExactMatchRule("region", "us")),
+		},
+		"optional/present and passes": {
+			rule: Optional(WithCustomClaimExactMatchRule("tenant", "acme")),
+		},
+		"optional/present and fails": {
+			rule:    Optional(WithCustomClaimExactMatchRule("tenant", "other")),
+			wantErr: "claim 'tenant' is invalid: expected 'other' but got 'acme'",
+		},
+		"optional/nested present and passes": {
+			rule: Optional(WithCustomClaimExactMatchRule("act.sub", "admin")),
+		},
+		"optional/scope claim and passes": {
+			rule: Optional(WithAllScopesRule("read")),
+		},
+		"optional/scope claim and fails": {
+			rule:    Optional(WithAllScopesRule("admin")),
+			wantErr: "claim 'scp' is invalid: missing scope(s): 'admin'",
+		},
+		"forbidden/scope claim": {
+			rule:    WithForbiddenClaimRule("scp"),
+			wantErr: "claim 'scope' must not be present",
+		},
+		"forbidden/nested present": {
+			rule:    WithForbiddenClaimRule("act.sub"),
+			wantErr: "claim 'act.sub' must not be present",
+		},
+		"forbidden/missing": {
+			rule: WithForbiddenClaimRule("nonce"),
+		},
+		"forbidden/present": {
+			rule:    WithForbiddenClaimRule("act"),
+			wantErr: "claim 'act' must not be present",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tt.rule.verify(context.Background(), JWT{Claims: claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithTokenRule(t *testing.T) {
+	now := time.Now().UTC()
+
+	cases := map[string]struct {
+		rule    ClaimRule
+		token   JWT
+		wantErr string
+	}{
+		"custom/passes": {
+			rule: WithTokenRule("kid is set", func(token JWT) error {
+				if token.Header["kid"] == nil {
+					return errors.New("missing kid")
+				}
+				return nil
+			}),
+			token: JWT{Header: map[string]any{"kid": "ee8d626d"}},
+		},
+		"custom/fails": {
+			rule: WithTokenRule("kid is set", func(token JWT) error {
+				if token.Header["kid"] == nil {
+					return errors.New("missing kid")
+				}
+				return nil
+			}),
+			token:   JWT{Header: map[string]any{}},
+			wantErr: "rule 'kid is set' failed: missing kid",
+		},
+		"claims equal/missing claim": {
+			rule: WithClaimsEqualRule("sub", "uid"),
+			token: JWT{Claims: map[string]any{
+				"sub": "00u1",
+			}},
+			wantErr: "rule 'claims_equal' failed: claim 'uid' not found",
+		},
+		"claims equal/fails": {
+			rule: WithClaimsEqualRule("azp", "cid"),
+			token: JWT{Claims: map[string]any{
+				"azp": "foo",
+				"cid": "bar",
+			}},
+			wantErr: "rule 'claims_equal' failed: claim 'azp' ('foo') does not equal claim 'cid' ('bar')",
+		},
+		"claims equal/passes": {
+			rule: WithClaimsEqualRule("azp", "cid"),
+			token: JWT{Claims: map[string]any{
+				"azp": "foo",
+				"cid": "foo",
+			}},
+		},
+		"max lifetime/invalid timestamp": {
+			rule: WithMaxLifetimeRule(time.Hour),
+			token: JWT{Claims: map[string]any{
+				"iat": float64(now.Unix()),
+				"exp": "foobar",
+			}},
+			wantErr: "rule 'max_lifetime' failed: claim 'exp' is invalid: expected a float64 but got a string",
+		},
+		"max lifetime/fails": {
+			rule: WithMaxLifetimeRule(time.Hour),
+			token: JWT{Claims: map[string]any{
+				"iat": float64(now.Unix()),
+				"exp": float64(now.Add(2 * time.Hour).Unix()),
+			}},
+			wantErr: "rule 'max_lifetime' failed: token lifetime of 2h0m0s exceeds 1h0m0s",
+		},
+		"max lifetime/passes with json numbers": {
+			rule: WithMaxLifetimeRule(time.Hour),
+			token: JWT{Claims: map[string]any{
+				"iat": json.Number(strconv.FormatInt(now.Unix(), 10)),
+				"exp": json.Number(strconv.FormatInt(now.Add(time.Hour).Unix(), 10)),
+			}},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tt.rule.verify(context.Background(), tt.token)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithContextRule(t *testing.T) {
+	type ctxKey struct{}
+
+	activeTenants := map[string]bool{"acme": true}
+	tenantRule := WithContextRule("tenant", func(ctx context.Context, value any) error {
+		if ctx.Value(ctxKey{}) != "request" {
+			return errors.New("context was not passed through")
+		}
+
+		if !activeTenants[value.(string)] {
+			return fmt.Errorf("tenant '%v' is not active", value)
+		}
+
+		return nil
+	})
+
+	slowRule := WithContextTokenRule("slow lookup", func(ctx context.Context, _ JWT) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+
+	cases := map[string]struct {
+		rule    ClaimRule
+		claims  map[string]any
+		wantErr string
+	}{
+		"missing claim": {
+			rule:    tenantRule,
+			claims:  map[string]any{},
+			wantErr: "claim 'tenant' not found",
+		},
+		"fails validation": {
+			rule: tenantRule,
+			claims: map[string]any{
+				"tenant": "globex",
+			},
+			wantErr: "claim 'tenant' is invalid: tenant 'globex' is not active",
+		},
+		"passes validation": {
+			rule: tenantRule,
+			claims: map[string]any{
+				"tenant": "acme",
+			},
+		},
+		"times out": {
+			rule:    WithTimeout(slowRule, 10*time.Millisecond),
+			claims:  map[string]any{},
+			wantErr: "rule 'slow lookup' failed: context deadline exceeded",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), ctxKey{}, "request")
+
+			err := tt.rule.verify(ctx, JWT{Claims: tt.claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_verifyRules(t *testing.T) {
+	// Each of the context-aware rules waits for the other to start, so they
+	// only pass if they are verified concurrently.
+	started := make(chan struct{}, 2)
+	waitForOther := func(ctx context.Context, _ JWT) error {
+		started <- struct{}{}
+		for len(started) < 2 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+		return errors.New("lookup failed")
+	}
+
+	rules := []ClaimRule{
+		WithTimeout(WithContextTokenRule("first", waitForOther), time.Second),
+		WithCustomClaimExactMatchRule("sub", "bar"),
+		WithTimeout(WithContextTokenRule("second", waitForOther), time.Second),
+	}
+
+	errs := verifyRules(context.Background(), JWT{Claims: map[string]any{"sub": "foo"}}, rules)
+	assert.EqualError(
+		t,
+		errs,
+		"rule 'first' failed: lookup failed; claim 'sub' is invalid: expected 'bar' but got 'foo'; rule 'second' failed: lookup failed",
+	)
+}
+
+func Test_verifyRules_panic(t *testing.T) {
+	rules := []ClaimRule{
+		WithContextRule("sub", func(context.Context, any) error {
+			panic("nil map")
+		}),
+		Not(WithContextTokenRule("lookup", func(context.Context, JWT) error {
+			panic("nil map")
+		})),
+	}
+
+	errs := verifyRules(context.Background(), JWT{Claims: map[string]any{"sub": "foo"}}, rules)
+	assert.EqualError(t, errs, "claim 'sub' is invalid: rule panicked: nil map; rule panicked: nil map")
+	assert.True(t, isIndeterminate(errs))
+}
+
+func TestWithCustomClaimContainsAnyRule(t *testing.T) {
+	cases := map[string]struct {
+		claims     map[string]any
+		wantValues []string
+		wantErr    string
+	}{
+		"wrong type": {
+			claims: map[string]any{
+				"foo": "bar",
+			},
+			wantErr: "expected an array but got a string",
+		},
+		"fails validation": {
+			claims: map[string]any{
+				"foo": []any{"bar", "hello"},
+			},
+			wantValues: []string{"world", "lmao"},
+			wantErr:    "missing any of value(s): 'world', 'lmao'",
+		},
+		"passes validation": {
+			claims: map[string]any{
+				"foo": []any{"bar", "hello", "world"},
+			},
+			wantValues: []string{"lmao", "world"},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			rule := WithCustomClaimContainsAnyRule("foo", tt.wantValues)
+			require.Equal(t, rule.Key, "foo")
+
+			err := rule.Rule(tt.claims[rule.Key])
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
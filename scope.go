@@ -0,0 +1,163 @@
+package verifier
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InsufficientScopeError is returned by the scope rules when the scopes of a
+// token do not satisfy the rule. It matches [ErrForbiddenScope] when used with
+// errors.Is if Forbidden is not empty, and [ErrInsufficientScope] otherwise.
+type InsufficientScopeError struct {
+	// Missing contains the scopes that the token is required to have but does
+	// not.
+	Missing []string
+	// Forbidden contains the scopes that the token has but must not have.
+	Forbidden []string
+
+	anyOf bool
+}
+
+// Error returns a description of the scopes that are missing or forbidden.
+func (e *InsufficientScopeError) Error() string {
+	if len(e.Forbidden) != 0 {
+		return fmt.Sprintf("forbidden scope(s) present: %s", quoteJoin(e.Forbidden))
+	}
+
+	if e.anyOf {
+		return fmt.Sprintf("missing any of scope(s): %s", quoteJoin(e.Missing))
+	}
+
+	return fmt.Sprintf("missing scope(s): %s", quoteJoin(e.Missing))
+}
+
+// Is reports whether target is ErrForbiddenScope or ErrInsufficientScope,
+// depending on whether Forbidden is empty.
+func (e *InsufficientScopeError) Is(target error) bool {
+	if len(e.Forbidden) != 0 {
+		return target == ErrForbiddenScope
+	}
+	return target == ErrInsufficientScope
+}
+
+// WithAllScopesRule returns a ClaimRule which will verify that the token has
+// all of the given scopes.
+//
+// The scopes are read from Okta's 'scp' claim, which is an array of strings,
+// or if that is not present then from the space-delimited 'scope' claim
+// described in RFC 9068.
+func WithAllScopesRule(scopes ...string) ClaimRule {
+	return withScopeRule("all_scopes", func(got []string) error {
+		missing := make([]string, 0)
+		for _, scope := range scopes {
+			if !slices.Contains(got, scope) {
+				missing = append(missing, scope)
+			}
+		}
+
+		if len(missing) != 0 {
+			return &InsufficientScopeError{Missing: missing}
+		}
+
+		return nil
+	})
+}
+
+// WithAnyScopeRule returns a ClaimRule which will verify that the token has
+// at least one of the given scopes.
+//
+// The scopes are read in the same way as [WithAllScopesRule].
+func WithAnyScopeRule(scopes ...string) ClaimRule {
+	return withScopeRule("any_scope", func(got []string) error {
+		for _, scope := range scopes {
+			if slices.Contains(got, scope) {
+				return nil
+			}
+		}
+
+		return &InsufficientScopeError{Missing: scopes, anyOf: true}
+	})
+}
+
+// WithNoScopesRule returns a ClaimRule which will verify that the token has
+// none of the given scopes. A token without any scopes passes the rule.
+//
+// The scopes are read in the same way as [WithAllScopesRule].
+func WithNoScopesRule(scopes ...string) ClaimRule {
+	return withScopeRule("no_scopes", func(got []string) error {
+		forbidden := make([]string, 0)
+		for _, scope := range scopes {
+			if slices.Contains(got, scope) {
+				forbidden = append(forbidden, scope)
+			}
+		}
+
+		if len(forbidden) != 0 {
+			return &InsufficientScopeError{Forbidden: forbidden}
+		}
+
+		return nil
+	})
+}
+
+func withScopeRule(name string, check func(got []string) error) ClaimRule {
+	return ClaimRule{
+		Key:     "scp",
+		name:    name,
+		aliases: claimAliases("scp"),
+		token: func(_ context.Context, token JWT) error {
+			got, err := parseScopes(token.Claims)
+			if err != nil {
+				return err
+			}
+
+			return check(got)
+		},
+	}
+}
+
+// claimAliases returns the other claims that are read in place of the given
+// claim, which are the 'scope' claim for the 'scp' claim, since the scope
+// rules read the scopes from either of them.
+func claimAliases(claim string) []string {
+	if claim == "scp" {
+		return []string{"scope"}
+	}
+	return nil
+}
+
+// parseScopes returns the scopes of a token from the 'scp' claim or, if that
+// is not present, from the 'scope' claim. If neither claim is present then no
+// scopes are returned.
+func parseScopes(claims map[string]any) ([]string, error) {
+	if value, ok := claims["scp"]; ok {
+		switch v := value.(type) {
+		case string:
+			return strings.Fields(v), nil
+		case []any:
+			scopes := make([]string, 0, len(v))
+			for _, raw := range v {
+				scope, ok := raw.(string)
+				if !ok {
+					return nil, fmt.Errorf("value of array element is not a %T", scope)
+				}
+				scopes = append(scopes, scope)
+			}
+			return scopes, nil
+		default:
+			return nil, fmt.Errorf("expected a string or an array but got a %T", value)
+		}
+	}
+
+	if value, ok := claims["scope"]; ok {
+		scope, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected claim 'scope' to be a %T but got a %T", scope, value)
+		}
+		return strings.Fields(scope), nil
+	}
+
+	return nil, nil
+}
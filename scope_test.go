@@ -0,0 +1,112 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeRules(t *testing.T) {
+	cases := map[string]struct {
+		rule        ClaimRule
+		claims      map[string]any
+		wantErr     string
+		wantRule    string
+		wantMissing []string
+	}{
+		"all/wrong type": {
+			rule: WithAllScopesRule("read"),
+			claims: map[string]any{
+				"scp": float64(1234),
+			},
+			wantErr:  "claim 'scp' is invalid: expected a string or an array but got a float64",
+			wantRule: "all_scopes",
+		},
+		"all/no scopes": {
+			rule:        WithAllScopesRule("read", "write"),
+			claims:      map[string]any{},
+			wantErr:     "claim 'scp' is invalid: missing scope(s): 'read', 'write'",
+			wantRule:    "all_scopes",
+			wantMissing: []string{"read", "write"},
+		},
+		"all/fails validation": {
+			rule: WithAllScopesRule("read", "write", "admin"),
+			claims: map[string]any{
+				"scp": []any{"openid", "read"},
+			},
+			wantErr:     "claim 'scp' is invalid: missing scope(s): 'write', 'admin'",
+			wantRule:    "all_scopes",
+			wantMissing: []string{"write", "admin"},
+		},
+		"all/passes validation": {
+			rule: WithAllScopesRule("read", "write"),
+			claims: map[string]any{
+				"scp": []any{"write", "openid", "read"},
+			},
+		},
+		"all/space-delimited scope claim": {
+			rule: WithAllScopesRule("read", "write"),
+			claims: map[string]any{
+				"scope": "write openid read",
+			},
+		},
+		"any/fails validation": {
+			rule: WithAnyScopeRule("write", "admin"),
+			claims: map[string]any{
+				"scope": "openid read",
+			},
+			wantErr:     "claim 'scp' is invalid: missing any of scope(s): 'write', 'admin'",
+			wantRule:    "any_scope",
+			wantMissing: []string{"write", "admin"},
+		},
+		"any/passes validation": {
+			rule: WithAnyScopeRule("write", "admin"),
+			claims: map[string]any{
+				"scp": []any{"openid", "admin"},
+			},
+		},
+		"none/fails validation": {
+			rule: WithNoScopesRule("write", "admin", "delete"),
+			claims: map[string]any{
+				"scp": []any{"admin", "openid", "delete"},
+			},
+			wantErr:  "claim 'scp' is invalid: forbidden scope(s) present: 'admin', 'delete'",
+			wantRule: "no_scopes",
+		},
+		"none/passes validation": {
+			rule:   WithNoScopesRule("write", "admin"),
+			claims: map[string]any{},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tt.rule.Key, "scp")
+
+			err := tt.rule.verify(context.Background(), JWT{Claims: tt.claims})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+
+				var verr *ValidationError
+				require.ErrorAs(t, verifyRules(context.Background(), JWT{Claims: tt.claims}, []ClaimRule{tt.rule}), &verr)
+				require.Len(t, verr.Failures, 1)
+				assert.Equal(t, tt.wantRule, verr.Failures[0].Rule)
+
+				var scopeErr *InsufficientScopeError
+				if errors.As(err, &scopeErr) && len(scopeErr.Forbidden) != 0 {
+					assert.ErrorIs(t, err, ErrForbiddenScope)
+					assert.NotErrorIs(t, err, ErrInsufficientScope)
+				} else if errors.As(err, &scopeErr) {
+					assert.ErrorIs(t, err, ErrInsufficientScope)
+					assert.NotErrorIs(t, err, ErrForbiddenScope)
+					assert.Equal(t, tt.wantMissing, scopeErr.Missing)
+				}
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
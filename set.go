@@ -0,0 +1,465 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// The event types of the Continuous Access Evaluation Profile (CAEP) and the
+// Risk Incident Sharing and Coordination (RISC) profile of the Shared
+// Signals Framework that are parsed into typed events.
+const (
+	EventTypeSessionRevoked       = "https://schemas.openid.net/secevent/caep/event-type/session-revoked"
+	EventTypeCredentialChange     = "https://schemas.openid.net/secevent/caep/event-type/credential-change"
+	EventTypeAccountDisabled      = "https://schemas.openid.net/secevent/risc/event-type/account-disabled"
+	EventTypeAccountPurged        = "https://schemas.openid.net/secevent/risc/event-type/account-purged"
+	EventTypeCredentialCompromise = "https://schemas.openid.net/secevent/risc/event-type/credential-compromise"
+	EventTypeSessionsRevoked      = "https://schemas.openid.net/secevent/risc/event-type/sessions-revoked"
+)
+
+const (
+	securityEventTokenType        = "secevent+jwt"
+	securityEventTokenContentType = "application/secevent+jwt"
+	maxSecurityEventTokenBytes    = 1 << 20
+)
+
+// SubjectIdentifier identifies the subject of a security event, as described
+// in RFC 9493. Simple subjects have a Format and the fields of that format,
+// such as Email for the "email" format. Complex subjects have the "complex"
+// format, or none, and identify the subject with one or more of User,
+// Session, Device and Tenant.
+type SubjectIdentifier struct {
+	Format      string `json:"format,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Issuer      string `json:"iss,omitempty"`
+	Subject     string `json:"sub,omitempty"`
+	ID          string `json:"id,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	URI         string `json:"uri,omitempty"`
+
+	User    *SubjectIdentifier `json:"user,omitempty"`
+	Session *SubjectIdentifier `json:"session,omitempty"`
+	Device  *SubjectIdentifier `json:"device,omitempty"`
+	Tenant  *SubjectIdentifier `json:"tenant,omitempty"`
+}
+
+// SecurityEvent is an event in a SecurityEventToken, such as a
+// *SessionRevokedEvent. Events of types that are not parsed into typed
+// events are *UnknownEvents.
+type SecurityEvent interface {
+	// EventType returns the URI that identifies the type of the event.
+	EventType() string
+	// EventSubject returns the subject of the event, which is either the
+	// 'subject' member of the event or the 'sub_id' claim of the token.
+	EventSubject() SubjectIdentifier
+}
+
+// CAEPEvent contains the members that all CAEP events have.
+type CAEPEvent struct {
+	Subject          SubjectIdentifier `json:"subject"`
+	EventTimestamp   int64             `json:"event_timestamp,omitempty"`
+	InitiatingEntity string            `json:"initiating_entity,omitempty"`
+	ReasonAdmin      map[string]string `json:"reason_admin,omitempty"`
+	ReasonUser       map[string]string `json:"reason_user,omitempty"`
+}
+
+// EventSubject returns the subject of the event.
+func (e CAEPEvent) EventSubject() SubjectIdentifier {
+	return e.Subject
+}
+
+func (e CAEPEvent) timestamp() int64 {
+	return e.EventTimestamp
+}
+
+// SessionRevokedEvent is a CAEP session revoked event, which means that the
+// session of the subject has been revoked.
+type SessionRevokedEvent struct {
+	CAEPEvent
+}
+
+// EventType returns EventTypeSessionRevoked.
+func (*SessionRevokedEvent) EventType() string {
+	return EventTypeSessionRevoked
+}
+
+// CredentialChangeEvent is a CAEP credential change event, which means that a
+// credential of the subject was created, changed, revoked or deleted.
+type CredentialChangeEvent struct {
+	CAEPEvent
+	CredentialType string `json:"credential_type"`
+	ChangeType     string `json:"change_type"`
+	FriendlyName   string `json:"friendly_name,omitempty"`
+	X509Issuer     string `json:"x509_issuer,omitempty"`
+	X509Serial     string `json:"x509_serial,omitempty"`
+	FIDO2AAGUID    string `json:"fido2_aaguid,omitempty"`
+}
+
+// EventType returns EventTypeCredentialChange.
+func (*CredentialChangeEvent) EventType() string {
+	return EventTypeCredentialChange
+}
+
+// RISCEvent contains the members that all RISC events have.
+type RISCEvent struct {
+	Subject SubjectIdentifier `json:"subject"`
+}
+
+// EventSubject returns the subject of the event.
+func (e RISCEvent) EventSubject() SubjectIdentifier {
+	return e.Subject
+}
+
+// AccountDisabledEvent is a RISC account disabled event, which means that the
+// account of the subject has been disabled.
+type AccountDisabledEvent struct {
+	RISCEvent
+	// Reason is why the account was disabled, such as "hijacking" or
+	// "bulk-account".
+	Reason string `json:"reason,omitempty"`
+}
+
+// EventType returns EventTypeAccountDisabled.
+func (*AccountDisabledEvent) EventType() string {
+	return EventTypeAccountDisabled
+}
+
+// AccountPurgedEvent is a RISC account purged event, which means that the
+// account of the subject has been permanently deleted.
+type AccountPurgedEvent struct {
+	RISCEvent
+}
+
+// EventType returns EventTypeAccountPurged.
+func (*AccountPurgedEvent) EventType() string {
+	return EventTypeAccountPurged
+}
+
+// CredentialCompromiseEvent is a RISC credential compromise event, which
+// means that a credential of the subject has been compromised.
+type CredentialCompromiseEvent struct {
+	RISCEvent
+	CredentialType string `json:"credential_type"`
+	ReasonAdmin    string `json:"reason_admin,omitempty"`
+	ReasonUser     string `json:"reason_user,omitempty"`
+}
+
+// EventType returns EventTypeCredentialCompromise.
+func (*CredentialCompromiseEvent) EventType() string {
+	return EventTypeCredentialCompromise
+}
+
+// SessionsRevokedEvent is a RISC sessions revoked event, which means that all
+// of the sessions of the subject have been revoked.
+type SessionsRevokedEvent struct {
+	RISCEvent
+}
+
+// EventType returns EventTypeSessionsRevoked.
+func (*SessionsRevokedEvent) EventType() string {
+	return EventTypeSessionsRevoked
+}
+
+// UnknownEvent is an event of a type that is not parsed into a typed event.
+type UnknownEvent struct {
+	Type    string
+	Subject SubjectIdentifier
+	// Payload is the value of the member of the 'events' claim for the event.
+	Payload map[string]any
+}
+
+// EventType returns the type of the event.
+func (e *UnknownEvent) EventType() string {
+	return e.Type
+}
+
+// EventSubject returns the subject of the event.
+func (e *UnknownEvent) EventSubject() SubjectIdentifier {
+	return e.Subject
+}
+
+// SecurityEventToken is a verified Security Event Token (SET), as described
+// in RFC 8417.
+type SecurityEventToken struct {
+	// ID is the value of the 'jti' claim.
+	ID string
+	// Subject is the value of the 'sub_id' claim, if present.
+	Subject *SubjectIdentifier
+	// Events are the events in the 'events' claim, ordered by type.
+	Events []SecurityEvent
+	// JWT is the verified SET.
+	JWT JWT
+}
+
+// ParseAndVerifySecurityEventToken will parse the Security Event Token (SET)
+// and verify it as described in RFC 8417, in addition to verifying it using
+// the provided rules. It verifies that:
+//
+//   - The 'typ' header parameter, if present, is 'secevent+jwt' or
+//     'application/secevent+jwt', compared case-insensitively.
+//   - The 'iss' claim equals the issuer of the Verifier.
+//   - The 'aud' claim contains the given audience.
+//   - The token was not issued in the future, allowing for the leeway set
+//     with WithLeeway.
+//   - The 'jti' claim is present.
+//   - The 'events' claim is an object whose members are all objects.
+//
+// The events are then parsed into the typed events in this package, such as
+// *SessionRevokedEvent, or into *UnknownEvents.
+func (j Verifier) ParseAndVerifySecurityEventToken(
+	ctx context.Context,
+	token string,
+	audience string,
+	rules ...ClaimRule,
+) (SecurityEventToken, error) {
+	setRules := []ClaimRule{
+		withSecurityEventTokenTypeRule(),
+		j.WithIssuerRule(),
+		WithAudienceRule(audience),
+		j.WithIssuedAtRule(j.leeway),
+		WithRequiredClaimRule("jti"),
+		WithRequiredClaimRule("events"),
+	}
+
+	parsed, err := j.ParseAndVerify(ctx, token, append(setRules, rules...)...)
+	if err != nil {
+		return SecurityEventToken{}, err
+	}
+
+	set := SecurityEventToken{
+		ID:  fmt.Sprint(parsed.Claims["jti"]),
+		JWT: parsed,
+	}
+
+	if subID, ok := parsed.Claims["sub_id"]; ok {
+		set.Subject = &SubjectIdentifier{}
+		if err = remarshal(subID, set.Subject); err != nil {
+			return SecurityEventToken{}, fmt.Errorf("claim 'sub_id' is invalid: %w", err)
+		}
+	}
+
+	if set.Events, err = parseSecurityEvents(parsed.Claims["events"], set.Subject); err != nil {
+		return SecurityEventToken{}, fmt.Errorf("claim 'events' is invalid: %w", err)
+	}
+
+	return set, nil
+}
+
+// withSecurityEventTokenTypeRule will verify that the 'typ' header parameter
+// of the token, if present, is "secevent+jwt" or "application/secevent+jwt",
+// as recommended by section 2.3 of RFC 8417.
+func withSecurityEventTokenTypeRule() ClaimRule {
+	return WithTokenRule("secevent_type", func(token JWT) error {
+		typ, ok := token.Header["typ"]
+		if !ok {
+			return nil
+		}
+		if s, ok := typ.(string); ok && isMediaType(s, securityEventTokenType) {
+			return nil
+		}
+		return fmt.Errorf("expected header 'typ' to be '%s' but got '%v'", securityEventTokenType, typ)
+	})
+}
+
+// isMediaType reports whether typ, the value of a 'typ' header parameter,
+// identifies the given media type, which does not have the "application/"
+// prefix. As described in section 4.1.9 of RFC 7515, the prefix may be left
+// out of typ, and media types are compared case-insensitively.
+func isMediaType(typ, mediaType string) bool {
+	const prefix = "application/"
+	if len(typ) > len(prefix) && strings.EqualFold(typ[:len(prefix)], prefix) {
+		typ = typ[len(prefix):]
+	}
+
+	return strings.EqualFold(typ, mediaType)
+}
+
+// parseSecurityEvents parses the 'events' claim of a SET into typed events.
+// Events without a 'subject' member are given the 'sub_id' claim as their
+// subject.
+func parseSecurityEvents(value any, subject *SubjectIdentifier) ([]SecurityEvent, error) {
+	members, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object but got a %T", value)
+	}
+
+	types := make([]string, 0, len(members))
+	for eventType := range members {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+
+	events := make([]SecurityEvent, 0, len(members))
+	for _, eventType := range types {
+		event, err := parseSecurityEvent(eventType, members[eventType], subject)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// parseSecurityEvent parses the member of the 'events' claim with the given
+// event type.
+func parseSecurityEvent(eventType string, value any, subject *SubjectIdentifier) (SecurityEvent, error) {
+	payload, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected member '%s' to be an object but got a %T", eventType, value)
+	}
+
+	if _, ok = payload["subject"]; !ok && subject != nil {
+		payload = maps.Clone(payload)
+		payload["subject"] = subject
+	}
+
+	event := newSecurityEvent(eventType)
+	if event == nil {
+		unknown := &UnknownEvent{Type: eventType, Payload: payload}
+		if err := remarshal(payload["subject"], &unknown.Subject); err != nil {
+			return nil, fmt.Errorf("parsing subject of member '%s': %w", eventType, err)
+		}
+		return unknown, nil
+	}
+
+	if err := remarshal(payload, event); err != nil {
+		return nil, fmt.Errorf("parsing member '%s': %w", eventType, err)
+	}
+
+	return event, nil
+}
+
+// newSecurityEvent returns a new event of the type identified by eventType,
+// or nil if it is not a known event type.
+func newSecurityEvent(eventType string) SecurityEvent {
+	switch eventType {
+	case EventTypeSessionRevoked:
+		return &SessionRevokedEvent{}
+	case EventTypeCredentialChange:
+		return &CredentialChangeEvent{}
+	case EventTypeAccountDisabled:
+		return &AccountDisabledEvent{}
+	case EventTypeAccountPurged:
+		return &AccountPurgedEvent{}
+	case EventTypeCredentialCompromise:
+		return &CredentialCompromiseEvent{}
+	case EventTypeSessionsRevoked:
+		return &SessionsRevokedEvent{}
+	default:
+		return nil
+	}
+}
+
+// remarshal converts a value decoded from JSON, such as a map[string]any,
+// into the type of dst.
+func remarshal(value, dst any) error {
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+// SecurityEventReceiver returns an http.Handler which receives Security Event
+// Tokens (SETs) that are pushed to it, as described in RFC 8935, such as the
+// Shared Signals Framework events that Okta transmits. It verifies each SET
+// with [Verifier.ParseAndVerifySecurityEventToken] using the given audience
+// and then calls handle with it.
+//
+// It responds with 202 if handle succeeds. If the SET is invalid, it responds
+// with 400 and one of the error codes of RFC 8935, so that the transmitter
+// does not retry it. If the keys of the issuer could not be fetched, it
+// responds with 503, and if handle fails, it responds with 500, so that the
+// transmitter retries the SET later.
+func (j Verifier) SecurityEventReceiver(
+	audience string,
+	handle func(ctx context.Context, set SecurityEventToken) error,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != securityEventTokenContentType {
+			writeSETError(w, "invalid_request", "The content type must be "+securityEventTokenContentType)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxSecurityEventTokenBytes))
+		if err != nil {
+			writeSETError(w, "invalid_request", "The request body could not be read")
+			return
+		}
+
+		set, err := j.ParseAndVerifySecurityEventToken(r.Context(), string(body), audience)
+		if errors.Is(err, ErrKeyFetch) {
+			http.Error(w, "The SET could not be verified", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			code, description := setErrorCode(err)
+			writeSETError(w, code, description)
+			return
+		}
+
+		if err = handle(r.Context(), set); err != nil {
+			http.Error(w, "The security event could not be processed", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// setErrorCode returns the error code of RFC 8935 and a description for an
+// error returned by ParseAndVerifySecurityEventToken.
+func setErrorCode(err error) (string, string) {
+	switch {
+	case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrUnknownKID):
+		return "invalid_key", "The SET could not be verified with the keys of the issuer"
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		failed := func(rule string) bool {
+			return slices.ContainsFunc(verr.Failures, func(f ClaimFailure) bool { return f.Rule == rule })
+		}
+
+		switch {
+		case failed("issuer"):
+			return "invalid_issuer", "The SET issuer is invalid"
+		case failed("audience"):
+			return "invalid_audience", "The SET audience is invalid"
+		}
+	}
+
+	return "invalid_request", "The SET is invalid"
+}
+
+// writeSETError responds to a SET push request with an error, as described in
+// section 2.3 of RFC 8935.
+func writeSETError(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"err":         code,
+		"description": description,
+	})
+}
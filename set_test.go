@@ -0,0 +1,243 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_ParseAndVerifySecurityEventToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	cases := map[string]struct {
+		claims     jwt.MapClaims
+		header     map[string]any
+		wantEvents []SecurityEvent
+		wantErr    string
+	}{
+		"caep events": {
+			claims: jwt.MapClaims{
+				"sub_id": map[string]any{"format": "email", "email": "alice@example.com"},
+				"events": map[string]any{
+					EventTypeSessionRevoked: map[string]any{
+						"event_timestamp":   float64(1615304991),
+						"initiating_entity": "policy",
+						"reason_admin":      map[string]any{"en": "Landspeed Policy Violation"},
+					},
+					EventTypeCredentialChange: map[string]any{
+						"subject":         map[string]any{"format": "iss_sub", "iss": "https://idp.example.com", "sub": "00u1"},
+						"credential_type": "password",
+						"change_type":     "update",
+					},
+				},
+			},
+			wantEvents: []SecurityEvent{
+				&CredentialChangeEvent{
+					CAEPEvent: CAEPEvent{
+						Subject: SubjectIdentifier{Format: "iss_sub", Issuer: "https://idp.example.com", Subject: "00u1"},
+					},
+					CredentialType: "password",
+					ChangeType:     "update",
+				},
+				&SessionRevokedEvent{CAEPEvent: CAEPEvent{
+					Subject:          SubjectIdentifier{Format: "email", Email: "alice@example.com"},
+					EventTimestamp:   1615304991,
+					InitiatingEntity: "policy",
+					ReasonAdmin:      map[string]string{"en": "Landspeed Policy Violation"},
+				}},
+			},
+		},
+		"risc and unknown events": {
+			claims: jwt.MapClaims{
+				"events": map[string]any{
+					EventTypeAccountDisabled: map[string]any{
+						"subject": map[string]any{"format": "opaque", "id": "00u1"},
+						"reason":  "hijacking",
+					},
+					"https://example.com/event-type/custom": map[string]any{"foo": "bar"},
+				},
+			},
+			wantEvents: []SecurityEvent{
+				&UnknownEvent{
+					Type:    "https://example.com/event-type/custom",
+					Payload: map[string]any{"foo": "bar"},
+				},
+				&AccountDisabledEvent{
+					RISCEvent: RISCEvent{Subject: SubjectIdentifier{Format: "opaque", ID: "00u1"}},
+					Reason:    "hijacking",
+				},
+			},
+		},
+		"type with application prefix": {
+			claims:     jwt.MapClaims{"events": map[string]any{}},
+			header:     map[string]any{"typ": "application/secevent+jwt"},
+			wantEvents: []SecurityEvent{},
+		},
+		"type in upper case": {
+			claims:     jwt.MapClaims{"events": map[string]any{}},
+			header:     map[string]any{"typ": "SECEVENT+JWT"},
+			wantEvents: []SecurityEvent{},
+		},
+		"wrong type": {
+			claims:  jwt.MapClaims{"events": map[string]any{}},
+			header:  map[string]any{"typ": "JWT"},
+			wantErr: "expected header 'typ' to be 'secevent+jwt' but got 'JWT'",
+		},
+		"missing events": {
+			claims:  jwt.MapClaims{},
+			wantErr: "claim 'events' not found",
+		},
+		"event is not an object": {
+			claims:  jwt.MapClaims{"events": map[string]any{EventTypeSessionRevoked: "revoked"}},
+			wantErr: "claim 'events' is invalid: expected member",
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := jwt.MapClaims{
+				"iss": issuer.URL,
+				"aud": "https://rp.example.com",
+				"iat": time.Now().Unix(),
+				"jti": "4d3559ec67504aaba65d40b0363faad8",
+			}
+			for k, v := range tt.claims {
+				claims[k] = v
+			}
+
+			header := tt.header
+			if header == nil {
+				header = map[string]any{"typ": "secevent+jwt"}
+			}
+
+			set, err := issuer.verifier().ParseAndVerifySecurityEventToken(
+				context.Background(),
+				issuer.sign(t, claims, header),
+				"https://rp.example.com",
+			)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, "4d3559ec67504aaba65d40b0363faad8", set.ID)
+			assert.Equal(t, tt.wantEvents, set.Events)
+		})
+	}
+}
+
+func TestVerifier_SecurityEventReceiver(t *testing.T) {
+	issuer := newTestIssuer(t)
+	other := newTestIssuer(t)
+
+	newSET := func(t *testing.T, i *testIssuer, aud string) string {
+		return i.sign(t, jwt.MapClaims{
+			"iss": issuer.URL,
+			"aud": aud,
+			"iat": time.Now().Unix(),
+			"jti": "4d3559ec67504aaba65d40b0363faad8",
+			"events": map[string]any{
+				EventTypeSessionRevoked: map[string]any{
+					"subject": map[string]any{"format": "opaque", "id": "sess-1"},
+				},
+			},
+		}, map[string]any{"typ": "secevent+jwt"})
+	}
+
+	cases := map[string]struct {
+		method          string
+		contentType     string
+		body            string
+		handleErr       error
+		keysUnavailable bool
+		wantStatus      int
+		wantBody        string
+	}{
+		"accepted": {
+			method:      http.MethodPost,
+			contentType: "application/secevent+jwt",
+			body:        newSET(t, issuer, "https://rp.example.com"),
+			wantStatus:  http.StatusAccepted,
+		},
+		"wrong method": {
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		"wrong content type": {
+			method:      http.MethodPost,
+			contentType: "application/json",
+			body:        newSET(t, issuer, "https://rp.example.com"),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"err":"invalid_request","description":"The content type must be application/secevent+jwt"}`,
+		},
+		"invalid key": {
+			method:      http.MethodPost,
+			contentType: "application/secevent+jwt",
+			body:        newSET(t, other, "https://rp.example.com"),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"err":"invalid_key","description":"The SET could not be verified with the keys of the issuer"}`,
+		},
+		"invalid audience": {
+			method:      http.MethodPost,
+			contentType: "application/secevent+jwt",
+			body:        newSET(t, issuer, "https://other.example.com"),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"err":"invalid_audience","description":"The SET audience is invalid"}`,
+		},
+		"keys unavailable": {
+			method:          http.MethodPost,
+			contentType:     "application/secevent+jwt",
+			body:            newSET(t, issuer, "https://rp.example.com"),
+			keysUnavailable: true,
+			wantStatus:      http.StatusServiceUnavailable,
+		},
+		"handler failed": {
+			method:      http.MethodPost,
+			contentType: "application/secevent+jwt",
+			body:        newSET(t, issuer, "https://rp.example.com"),
+			handleErr:   errors.New("database unavailable"),
+			wantStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := issuer.verifier()
+			if tt.keysUnavailable {
+				v = New("http://127.0.0.1:0", WithCache(NewNopCache()))
+			}
+
+			var handled bool
+			receiver := v.SecurityEventReceiver(
+				"https://rp.example.com",
+				func(_ context.Context, set SecurityEventToken) error {
+					handled = true
+					require.Len(t, set.Events, 1)
+					return tt.handleErr
+				},
+			)
+
+			req := httptest.NewRequest(tt.method, "/events", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rec := httptest.NewRecorder()
+			receiver.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+			assert.Equal(t, tt.wantStatus == http.StatusAccepted || tt.handleErr != nil, handled)
+		})
+	}
+}
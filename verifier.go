@@ -8,9 +8,9 @@ import (
 	"io"
 	"net/http"
 	"path"
-	"strings"
 	"time"
 
+	"github.com/MicahParks/jwkset"
 	keyfunc "github.com/MicahParks/keyfunc/v3"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -24,6 +24,45 @@ const (
 // JWT represents the claims on a JWT.
 type JWT struct {
 	Claims map[string]any
+	// Header contains the parameters of the JOSE header of the JWT, such as
+	// 'kid', 'alg' and 'typ'.
+	Header map[string]any
+	// Raw is the token that the JWT was parsed from.
+	Raw string
+	// Verification describes how the signature of the JWT was verified.
+	Verification Verification
+}
+
+// Verification describes how the signature of a JWT was verified, which is
+// useful for audit logs and for debugging key rotation.
+type Verification struct {
+	// KeyID is the ID of the key that verified the signature, from the 'kid'
+	// header parameter.
+	KeyID string
+	// Algorithm is the algorithm of the signature, from the 'alg' header
+	// parameter.
+	Algorithm string
+	// Key is the public key that verified the signature, such as an
+	// *rsa.PublicKey.
+	Key any
+	// Issuer is the issuer whose JSON Web Key Set verified the signature,
+	// which is either the issuer of the Verifier or one of the aliases set
+	// with WithIssuerAliases.
+	Issuer string
+	// KeysFromCache is true if the JSON Web Key Set of the issuer was taken
+	// from the cache rather than fetched while verifying the JWT.
+	KeysFromCache bool
+	// KeysFetchedAt is when the JSON Web Key Set of the issuer was fetched.
+	KeysFetchedAt time.Time
+	// Encrypted is true if the JWT was encrypted, in which case the other
+	// fields describe the JWT inside of it.
+	Encrypted bool
+}
+
+// keySet is the value that is cached for the JSON Web Key Set of the issuer.
+type keySet struct {
+	keyfunc   jwt.Keyfunc
+	fetchedAt time.Time
 }
 
 // Cache is used to cache values.
@@ -43,7 +82,9 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
-// WithCache sets the cache that the Client should use.
+// WithCache sets the cache that the Client should use. The cache must
+// implement AddCache to verify DPoP proofs and logout tokens, which must be
+// protected against replay.
 func WithCache(cache Cache) Option {
 	return func(j *Verifier) {
 		j.cache = cache
@@ -66,14 +107,31 @@ func WithUseJSONNumber() Option {
 	}
 }
 
+// WithLeeway sets the leeway in seconds that is allowed for clock skew by the
+// checks of the 'exp' and 'iat' claims that [Verifier.ParseAndVerifyIDToken]
+// always performs. Defaults to 0.
+func WithLeeway(leeway int) Option {
+	return func(j *Verifier) {
+		j.leeway = leeway
+	}
+}
+
 // Verifier is used to parse and verify JWT tokens issued by Okta.
 type Verifier struct {
 	client            *http.Client
 	issuer            string
+	issuerAliases     []string
 	wellKnownEndpoint string
 	cache             Cache
 	useJSONNumber     bool
+	leeway            int
+	resourceServer    bool
 	now               func() time.Time
+
+	decryptionKeys              []decryptionKey
+	keyEncryptionAlgorithms     []string
+	contentEncryptionAlgorithms []string
+	requireEncryption           bool
 }
 
 // New creates a new Verifier.
@@ -97,48 +155,86 @@ func New(issuer string, opts ...Option) Verifier {
 // rules and return the parsed JWT. It will only verify the claims according to
 // the provided rules. If no rules are provided, it will not verify any of the
 // claims.
+//
+// If the token is valid but any of the rules fail, the error will be a
+// *ValidationError. Otherwise the error can be matched against the errors
+// in this package with errors.Is, such as ErrMalformed, ErrInvalidSignature,
+// ErrUnknownKID and ErrKeyFetch.
+//
+// If WithResourceServer is used and the issuer is the org authorization
+// server, it fails with ErrOrgAuthorizationServer without parsing the JWT.
+//
+// Encrypted tokens are decrypted using the keys set with WithDecryptionKey
+// before the JWT inside of them is verified. If decryption fails, the error
+// matches ErrDecryption.
+//
+// The rules are only verified once the signature of the JWT has been
+// verified. Rules that use the context, such as those created with
+// [WithContextRule], are verified concurrently with each other.
 func (j Verifier) ParseAndVerify(ctx context.Context, token string, rules ...ClaimRule) (JWT, error) {
-	parsed, err := j.parseJWT(ctx, token)
-	if err != nil {
+	if err := j.checkResourceServer(); err != nil {
 		return JWT{}, err
 	}
 
-	claims, ok := parsed.Claims.(jwt.MapClaims)
-	if !ok {
-		return JWT{}, fmt.Errorf("parsed claims are not %T", claims)
-	}
+	signed := token
+	encrypted := isEncrypted(token)
 
-	verificationErrors := make([]string, 0)
-	for _, rule := range rules {
-		v, ok := claims[rule.Key]
-		if !ok {
-			verificationErrors = append(verificationErrors, fmt.Sprintf("claim '%s' not found", rule.Key))
-			continue
+	switch {
+	case encrypted:
+		var err error
+		if signed, err = j.decrypt(token); err != nil {
+			return JWT{}, err
 		}
+	case j.requireEncryption:
+		return JWT{}, ErrEncryptionRequired
+	}
 
-		if rule.Rule == nil {
-			continue
-		}
+	parsed, verification, err := j.parseJWT(ctx, signed)
+	if err != nil {
+		return JWT{}, err
+	}
+	verification.Encrypted = encrypted
 
-		if err = rule.Rule(v); err != nil {
-			verificationErrors = append(
-				verificationErrors,
-				fmt.Sprintf("claim '%s' is invalid: %s", rule.Key, err.Error()),
-			)
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return JWT{}, &sentinelError{
+			sentinel: ErrMalformed,
+			err:      fmt.Errorf("parsed claims are not %T", claims),
 		}
 	}
 
-	if len(verificationErrors) != 0 {
-		return JWT{}, errors.New(strings.Join(verificationErrors, "; "))
+	parsedJWT := JWT{
+		Claims:       claims,
+		Header:       parsed.Header,
+		Raw:          token,
+		Verification: verification,
+	}
+
+	if verr := verifyRules(ctx, parsedJWT, rules); verr != nil {
+		return JWT{}, verr
 	}
 
-	return JWT{Claims: claims}, nil
+	return parsedJWT, nil
 }
 
-func (j Verifier) parseJWT(ctx context.Context, tokenString string) (*jwt.Token, error) {
-	kf, err := j.getKeyfunc(ctx)
+func (j Verifier) parseJWT(ctx context.Context, tokenString string) (*jwt.Token, Verification, error) {
+	issuer := j.keysIssuer(tokenString)
+
+	keys, fromCache, err := j.getKeySet(ctx, issuer)
 	if err != nil {
-		return nil, err
+		return nil, Verification{}, &sentinelError{sentinel: ErrKeyFetch, err: err}
+	}
+
+	verification := Verification{
+		Issuer:        issuer,
+		KeysFromCache: fromCache,
+		KeysFetchedAt: keys.fetchedAt,
+	}
+
+	kf := func(token *jwt.Token) (any, error) {
+		key, err := keys.keyfunc(token)
+		verification.Key = key
+		return key, err
 	}
 
 	options := []jwt.ParserOption{jwt.WithoutClaimsValidation()}
@@ -148,39 +244,67 @@ func (j Verifier) parseJWT(ctx context.Context, tokenString string) (*jwt.Token,
 
 	token, err := jwt.Parse(tokenString, kf, options...)
 	if err != nil {
-		return nil, fmt.Errorf("parsing jwt: %w", err)
+		err = fmt.Errorf("parsing jwt: %w", err)
+
+		switch {
+		case errors.Is(err, jwkset.ErrKeyNotFound):
+			return nil, Verification{}, &sentinelError{sentinel: ErrUnknownKID, err: err}
+		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+			return nil, Verification{}, &sentinelError{sentinel: ErrInvalidSignature, err: err}
+		case errors.Is(err, jwt.ErrTokenMalformed):
+			return nil, Verification{}, &sentinelError{sentinel: ErrMalformed, err: err}
+		}
+
+		return nil, Verification{}, err
 	}
 
-	return token, nil
+	verification.KeyID, _ = token.Header["kid"].(string)
+	verification.Algorithm = token.Method.Alg()
+
+	return token, verification, nil
 }
 
-func (j Verifier) getKeyfunc(ctx context.Context) (jwt.Keyfunc, error) {
-	if v, ok := j.cache.Get(ctx, cacheKeyKeyfunc); ok {
-		return v.(jwt.Keyfunc), nil
+// getKeySet returns the JSON Web Key Set of the given issuer, and whether it
+// was taken from the cache.
+func (j Verifier) getKeySet(ctx context.Context, issuer string) (keySet, bool, error) {
+	cacheKey := cacheKeyKeyfunc
+	if issuer != j.issuer {
+		cacheKey += ":" + issuer
 	}
 
-	jwksURI, err := j.getJWKSURI(ctx)
+	if v, ok := j.cache.Get(ctx, cacheKey); ok {
+		if keys, ok := v.(keySet); ok {
+			return keys, true, nil
+		}
+	}
+
+	jwksURI, err := j.getJWKSURI(ctx, issuer)
 	if err != nil {
-		return nil, fmt.Errorf("getting jwks uri: %w", err)
+		return keySet{}, false, fmt.Errorf("getting jwks uri: %w", err)
 	}
 
 	data, err := j.getJWKS(ctx, jwksURI)
 	if err != nil {
-		return nil, fmt.Errorf("getting jwks: %w", err)
+		return keySet{}, false, fmt.Errorf("getting jwks: %w", err)
 	}
 
 	fn, err := keyfunc.NewJWKSetJSON(data)
 	if err != nil {
-		return nil, fmt.Errorf("creating new key func: %w", err)
+		return keySet{}, false, fmt.Errorf("creating new key func: %w", err)
+	}
+
+	keys := keySet{
+		keyfunc:   fn.Keyfunc,
+		fetchedAt: j.now(),
 	}
 
-	j.cache.Set(ctx, cacheKeyKeyfunc, jwt.Keyfunc(fn.Keyfunc))
+	j.cache.Set(ctx, cacheKey, keys)
 
-	return fn.Keyfunc, nil
+	return keys, false, nil
 }
 
-func (j Verifier) getJWKSURI(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.issuer, nil)
+func (j Verifier) getJWKSURI(ctx context.Context, issuer string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer, nil)
 	if err != nil {
 		return "", fmt.Errorf("creating new *http.Request: %w", err)
 	}